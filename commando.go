@@ -26,11 +26,19 @@
 package commando
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"text/template"
+	"time"
 
 	"github.com/thatisuday/clapper"
 )
@@ -47,8 +55,72 @@ const (
 
 	// string data type
 	String
+
+	// float64 data type
+	Float
+
+	// uint data type
+	Uint
+
+	// int64 data type
+	Int64
+
+	// time.Duration data type (e.g. `1m30s`)
+	Duration
+
+	// []string data type (repeatable flag)
+	StringSlice
+
+	// []int data type (repeatable flag)
+	IntSlice
+
+	// map[string]string data type (repeatable flag with `key=value` values)
+	StringMap
+
+	// custom data type of a `Value` implementation (see `AddFlagValue`)
+	Custom
 )
 
+// default delimiter of the values of a repeatable flag
+const defaultFlagDelimiter = ","
+
+// Go types of the data types (for the error messages of the default-values)
+var dataTypeGoNames = map[int]string{
+	Int:         "an int",
+	String:      "a string",
+	Float:       "a float64",
+	Uint:        "a uint",
+	Int64:       "an int64",
+	Duration:    "a time.Duration",
+	StringSlice: "a []string",
+	IntSlice:    "a []int",
+	StringMap:   "a map[string]string",
+}
+
+// Go types of the data types of the arguments
+var dataTypeGoTypes = map[int]reflect.Type{
+	String:   reflect.TypeOf(""),
+	Int:      reflect.TypeOf(0),
+	Float:    reflect.TypeOf(0.0),
+	Uint:     reflect.TypeOf(uint(0)),
+	Int64:    reflect.TypeOf(int64(0)),
+	Duration: reflect.TypeOf(time.Duration(0)),
+}
+
+// descriptions of the data types (for the error messages of the command-line argument values)
+var dataTypeDescs = map[int]string{
+	Bool:        "a boolean",
+	Int:         "an integer",
+	String:      "a string",
+	Float:       "a number",
+	Uint:        "a non-negative integer",
+	Int64:       "an integer",
+	Duration:    "a duration (e.g. 1m30s)",
+	StringSlice: "a list of strings",
+	IntSlice:    "a list of integers",
+	StringMap:   "a list of key=value pairs",
+}
+
 // root-command name
 var rootCommandName = ""
 
@@ -57,6 +129,8 @@ var (
 	helpCommandName         = "help"
 	helpCommandDesc         = "This command displays the usage information of this CLI application."
 	helpCommandShortDesc    = "displays usage information"
+	helpCommandArgName      = "command"
+	helpCommandArgDesc      = "command to display the usage information of"
 	versionCommandName      = "version"
 	versionCommandDesc      = "This command displays the version number of this CLI application"
 	versionCommandShortDesc = "displays version number"
@@ -68,12 +142,27 @@ var (
 	versionFlagDesc         = "displays version number"
 )
 
+// maximum edit distance of the suggestions for unknown commands and flags
+const defaultSuggestionDistance = 2
+
 // event names
 const (
 	EventVersion = "version"
 	EventHelp    = "help"
 )
 
+// default exit codes of the process
+const (
+	// ExitCodeSuccess is used when an action function succeeds or usage/version information is displayed
+	ExitCodeSuccess = 0
+
+	// ExitCodeFailure is used when an action function fails or the registry is misconfigured
+	ExitCodeFailure = 1
+
+	// ExitCodeUsage is used when the command-line arguments are invalid
+	ExitCodeUsage = 2
+)
+
 /********************************************/
 
 // check if command is the root command
@@ -91,6 +180,257 @@ func trimWhitespaces(value string) string {
 	return strings.Trim(value, " ")
 }
 
+// check if a default-value has the Go type of a data type
+func isDataTypeValue(dataType int, value interface{}) bool {
+	switch value.(type) {
+	case int:
+		return dataType == Int
+	case string:
+		return dataType == String
+	case float64:
+		return dataType == Float
+	case uint:
+		return dataType == Uint
+	case int64:
+		return dataType == Int64
+	case time.Duration:
+		return dataType == Duration
+	case []string:
+		return dataType == StringSlice
+	case []int:
+		return dataType == IntSlice
+	case map[string]string:
+		return dataType == StringMap
+	}
+
+	return false
+}
+
+// check if a data type accumulates the values of a repeated flag
+func isRepeatableDataType(dataType int) bool {
+	return dataType == StringSlice || dataType == IntSlice || dataType == StringMap
+}
+
+// convert the command-line argument values of a repeated flag to the Go type of a data type.
+// Each value is split by the delimiter (if not empty) and empty items are ignored.
+func convertValues(dataType int, values []string, delimiter string) (interface{}, error) {
+	items := splitValues(values, delimiter)
+
+	switch dataType {
+	case IntSlice:
+		ints := make([]int, 0, len(items))
+		for _, item := range items {
+			_value, err := strconv.ParseInt(item, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			ints = append(ints, int(_value))
+		}
+
+		return ints, nil
+	case StringMap:
+		pairs := make(map[string]string)
+		for _, item := range items {
+			index := strings.Index(item, "=")
+			if index < 1 {
+				return nil, fmt.Errorf("%s is not a key=value pair", item)
+			}
+
+			pairs[item[:index]] = item[index+1:]
+		}
+
+		return pairs, nil
+	}
+
+	return items, nil
+}
+
+// split the command-line argument values of a repeated flag by the delimiter (if not empty) and remove empty items
+func splitValues(values []string, delimiter string) []string {
+	items := make([]string, 0)
+	for _, value := range values {
+		parts := []string{value}
+		if delimiter != "" {
+			parts = strings.Split(value, delimiter)
+		}
+
+		for _, part := range parts {
+			if part != "" {
+				items = append(items, part)
+			}
+		}
+	}
+
+	return items
+}
+
+// check if all the values are one of the choices (any value is valid without choices)
+func isChoice(choices []string, values ...string) bool {
+	if len(choices) == 0 {
+		return true
+	}
+
+	for _, value := range values {
+		valid := false
+		for _, choice := range choices {
+			if value == choice {
+				valid = true
+			}
+		}
+
+		if !valid {
+			return false
+		}
+	}
+
+	return true
+}
+
+// copy the default-value of a repeatable flag (an action function may modify the value)
+func copyValues(value interface{}) interface{} {
+	switch value := value.(type) {
+	case []string:
+		return append([]string{}, value...)
+	case []int:
+		return append([]int{}, value...)
+	case map[string]string:
+		pairs := make(map[string]string)
+		for key, _value := range value {
+			pairs[key] = _value
+		}
+
+		return pairs
+	}
+
+	return value
+}
+
+// format the default-value of a repeatable flag (items separated by the delimiter)
+func formatValues(value interface{}, delimiter string) string {
+	items := make([]string, 0)
+	switch value := value.(type) {
+	case []string:
+		items = append(items, value...)
+	case []int:
+		for _, item := range value {
+			items = append(items, strconv.Itoa(item))
+		}
+	case map[string]string:
+		for key, _value := range value {
+			items = append(items, key+"="+_value)
+		}
+
+		sort.Strings(items)
+	}
+
+	if delimiter == "" {
+		delimiter = defaultFlagDelimiter
+	}
+
+	return strings.Join(items, delimiter)
+}
+
+// get the zero value of the Go type of a data type (empty slice or map for a repeatable data type)
+func zeroValue(dataType int) interface{} {
+	switch dataType {
+	case Bool:
+		return false
+	case StringSlice:
+		return []string{}
+	case IntSlice:
+		return []int{}
+	case StringMap:
+		return map[string]string{}
+	}
+
+	return reflect.Zero(dataTypeGoTypes[dataType]).Interface()
+}
+
+// convert a command-line argument value to the Go type of a data type
+func convertValue(dataType int, value string) (interface{}, error) {
+	switch dataType {
+	case Bool:
+		return strconv.ParseBool(value)
+	case Int:
+		_value, err := strconv.ParseInt(value, 10, 64)
+		return int(_value), err
+	case Float:
+		return strconv.ParseFloat(value, 64)
+	case Uint:
+		_value, err := strconv.ParseUint(value, 10, 64)
+		return uint(_value), err
+	case Int64:
+		return strconv.ParseInt(value, 10, 64)
+	case Duration:
+		return time.ParseDuration(value)
+	}
+
+	return value, nil
+}
+
+// check if a command-line argument value is a flag (same as `clapper`)
+func isFlag(value string) bool {
+	return len(value) >= 2 && strings.HasPrefix(value, "-")
+}
+
+// calculate the Levenshtein (edit) distance between two strings
+func editDistance(a string, b string) int {
+	source, target := []rune(a), []rune(b)
+
+	// distances of the previous row
+	previous := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(source); i++ {
+		current := make([]int, len(target)+1)
+		current[0] = i
+
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+
+			current[j] = previous[j] + 1 // deletion
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1 // insertion
+			}
+			if previous[j-1]+cost < current[j] {
+				current[j] = previous[j-1] + cost // substitution
+			}
+		}
+
+		previous = current
+	}
+
+	return previous[len(target)]
+}
+
+// create a context which is cancelled when the process receives an interrupt or a termination signal
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// listen for signals until the context is cancelled
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+
+		// a second signal terminates the process
+		signal.Stop(signals)
+	}()
+
+	return ctx, cancel
+}
+
 /********************************************/
 
 // CommandRegistry holds the registered command configurations.
@@ -112,6 +452,42 @@ type CommandRegistry struct {
 	// event listener for version, help etc. events
 	EventListener func(string)
 
+	// exit code for usage errors (default: `ExitCodeUsage`)
+	UsageExitCode int
+
+	// exit code for action and configuration errors (default: `ExitCodeFailure`)
+	FailureExitCode int
+
+	// maximum edit distance of the suggestions for unknown commands and flags (`0` disables suggestions)
+	SuggestionDistance int
+
+	// display commands and flags in the alphabetical order instead of the registration order
+	SortHelp bool
+
+	// prefix of the environment variables bound to the flags automatically (see `SetEnvPrefix`)
+	EnvPrefix string
+
+	// path of the default configuration file (see `SetConfigFile`)
+	ConfigFile string
+
+	// long-name of the flag which overrides the path of the configuration file (see `SetConfigFlag`)
+	configFlag string
+
+	// middleware which wraps the action functions of all commands (see `Use`)
+	Middlewares []Middleware
+
+	// writer of the usage information, the version and the completion scripts (default: `os.Stdout`)
+	Stdout io.Writer
+
+	// writer of the error messages (default: `os.Stderr`)
+	Stderr io.Writer
+
+	// shell completion is enabled (the hidden `__complete` command is accepted)
+	completion bool
+
+	// number of registered commands and flags (to remember the registration order)
+	registrations int
+
 	// registry to hold `clapper` registry object
 	registry clapper.Registry
 }
@@ -119,6 +495,13 @@ type CommandRegistry struct {
 // AddCommand adds a command in the registry.
 func (cr *CommandRegistry) addCommand(name string) *Command {
 
+	// name of the command should not be an alias of another command
+	if root, ok := cr.Commands[rootCommandName]; ok {
+		if command := aliasOwner(cr.subCommands(root), name); command != nil {
+			cr.fail("%s command conflicts with an alias of the %s command", name, command.Path())
+		}
+	}
+
 	// register command with clapper
 	clpCommandConfig, exists := cr.registry.Register(name)
 
@@ -129,13 +512,8 @@ func (cr *CommandRegistry) addCommand(name string) *Command {
 
 	/*---------------------------*/
 
-	// create a command config
-	c := &Command{
-		clpCommandConfig: clpCommandConfig,
-		IsRoot:           clpCommandConfig.Name == rootCommandName,
-		Args:             make(map[string]*Arg),
-		Flags:            make(map[string]*Flag),
-	}
+	// create a command config (top-level commands are sub-commands of the root-command)
+	c := cr.newCommand(cr.Commands[rootCommandName], clpCommandConfig)
 
 	/*---------------------------*/
 
@@ -149,12 +527,252 @@ func (cr *CommandRegistry) addCommand(name string) *Command {
 
 }
 
+// get the registration order of a new command or flag
+func (cr *CommandRegistry) nextOrder() int {
+	cr.registrations++
+
+	return cr.registrations
+}
+
+// create a command config
+func (cr *CommandRegistry) newCommand(parent *Command, clpCommandConfig *clapper.CommandConfig) *Command {
+	return &Command{
+		order:            cr.nextOrder(),
+		commandRegistry:  cr,
+		clpCommandConfig: clpCommandConfig,
+		clpRegistry:      clapper.NewRegistry(),
+		parent:           parent,
+		IsRoot:           parent == nil,
+		Args:             make(map[string]*Arg),
+		Flags:            make(map[string]*Flag),
+		PersistentFlags:  make(map[string]*Flag),
+		Commands:         make(map[string]*Command),
+	}
+}
+
+// get all registered commands (including nested sub-commands)
+func (cr *CommandRegistry) allCommands() []*Command {
+	commands := make([]*Command, 0)
+
+	// add a command and its sub-commands
+	var add func(*Command)
+	add = func(c *Command) {
+		commands = append(commands, c)
+
+		for _, command := range c.Commands {
+			add(command)
+		}
+	}
+
+	for _, command := range cr.Commands {
+		add(command)
+	}
+
+	return commands
+}
+
+// get sub-commands of a command (top-level commands for the root-command)
+func (cr *CommandRegistry) subCommands(c *Command) map[string]*Command {
+	if !c.IsRoot {
+		return c.Commands
+	}
+
+	commands := make(map[string]*Command)
+	for name, command := range cr.Commands {
+		if !command.IsRoot {
+			commands[name] = command
+		}
+	}
+
+	return commands
+}
+
+// find a sub-command of a command by its name or alias
+func (cr *CommandRegistry) findSubCommand(c *Command, name string) (*Command, bool) {
+	commands := cr.subCommands(c)
+
+	if command, ok := commands[name]; ok {
+		return command, true
+	}
+
+	if command := aliasOwner(commands, name); command != nil {
+		return command, true
+	}
+
+	return nil, false
+}
+
+// find a command which uses the name as an alias
+func aliasOwner(commands map[string]*Command, name string) *Command {
+	for _, command := range commands {
+		for _, alias := range command.Aliases {
+			if alias == name {
+				return command
+			}
+		}
+	}
+
+	return nil
+}
+
+// get names similar to `name` sorted by their edit distance
+func (cr *CommandRegistry) suggest(name string, candidates []string) []string {
+	suggestions := make([]string, 0)
+	distances := make(map[string]int)
+
+	if cr.SuggestionDistance <= 0 {
+		return suggestions
+	}
+
+	for _, candidate := range candidates {
+		if _, ok := distances[candidate]; ok {
+			continue
+		}
+
+		// distance is calculated case-insensitively and it should be less than
+		// the length of the name (otherwise all short flag names are similar)
+		distance := editDistance(strings.ToLower(name), strings.ToLower(candidate))
+		if distance <= cr.SuggestionDistance && distance < len(strings.TrimLeft(name, "-")) {
+			distances[candidate] = distance
+			suggestions = append(suggestions, candidate)
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if distances[suggestions[i]] == distances[suggestions[j]] {
+			return suggestions[i] < suggestions[j]
+		}
+
+		return distances[suggestions[i]] < distances[suggestions[j]]
+	})
+
+	return suggestions
+}
+
+// create an error for an unknown sub-command of a command with suggestions of similar sub-commands
+func (cr *CommandRegistry) unknownCommandError(c *Command, name string) error {
+	candidates := make([]string, 0)
+	for commandName, command := range cr.subCommands(c) {
+		candidates = append(candidates, commandName)
+		candidates = append(candidates, command.Aliases...)
+	}
+
+	err := newParseError(ErrUnknownCommand, name, clapper.ErrorUnknownCommand{Name: name}, "%s is not a valid command", name)
+	err.Suggestions = cr.suggest(name, candidates)
+
+	return err
+}
+
+// create an error for an unknown flag of a command with suggestions of similar flags
+func (cr *CommandRegistry) unknownFlagError(c *Command, err clapper.ErrorUnknownFlag) error {
+	candidates := make([]string, 0)
+	for _, flags := range []map[string]*Flag{c.Flags, c.inheritedFlags()} {
+		for name, flag := range flags {
+			if flag.ClpFlag.IsInverted {
+				candidates = append(candidates, "--no-"+name)
+			} else {
+				candidates = append(candidates, "--"+name)
+			}
+
+			if flag.ClpFlag.ShortName != "" {
+				candidates = append(candidates, "-"+flag.ClpFlag.ShortName)
+			}
+		}
+	}
+
+	parseError := newParseError(ErrUnknownFlag, err.Name, err, "%s is not a valid flag", err.Name)
+	parseError.Suggestions = cr.suggest(err.Name, candidates)
+
+	return parseError
+}
+
+// find a command using the names of the command and its parent commands (e.g. `cluster node add`).
+// If the `path` is empty, the root-command is returned.
+func (cr *CommandRegistry) lookupCommand(path []string) (*Command, error) {
+	command := cr.Commands[rootCommandName]
+
+	for _, name := range path {
+		subCommand, ok := cr.findSubCommand(command, name)
+		if !ok {
+			return nil, cr.unknownCommandError(command, name)
+		}
+
+		command = subCommand
+	}
+
+	return command, nil
+}
+
+// get the command `clapper` parses the values for (same as `clapper`)
+func parsedCommand(commands map[string]*Command, values []string) *Command {
+	if len(values) > 0 {
+		if command, ok := commands[values[0]]; ok {
+			return command
+		}
+	}
+
+	return commands[rootCommandName]
+}
+
+// find the command to execute when it is a nested sub-command.
+// It returns the parent command (`nil` for the root-command and top-level commands)
+// and the command-line argument values to parse with the `clapper` registry of the parent command.
+func (cr *CommandRegistry) findCommand(values []string) (*Command, []string, error) {
+	root := cr.Commands[rootCommandName]
+
+	// walk through sub-commands (flags and their values before the name of a sub-command are skipped)
+	var parent *Command
+	command, flags := root, make([]string, 0)
+	index := 0
+
+	for ; index < len(values); index++ {
+		value := values[index]
+
+		if isFlag(value) {
+			flags = append(flags, value)
+
+			// value of a flag of the command which takes a value (`--name value`)
+			if flag := command.findFlag(value); flag != nil && flag.DataType != Bool && index+1 < len(values) && !isFlag(values[index+1]) {
+				index++
+				flags = append(flags, values[index])
+			}
+
+			continue
+		}
+
+		subCommand, ok := cr.findSubCommand(command, value)
+
+		if !ok {
+			// a command with sub-commands and without arguments can not take a value
+			if command != root && len(command.Commands) > 0 && len(command.Args) == 0 {
+				return nil, nil, cr.unknownCommandError(command, value)
+			}
+
+			break
+		}
+
+		if command != root {
+			parent = command
+		}
+		command = subCommand
+	}
+
+	// root-command or an unknown command (handled by `clapper`)
+	if command == root {
+		return nil, values, nil
+	}
+
+	// replace aliases with the name of the command (flags before the name of the command are moved after it)
+	values = append(append([]string{command.clpCommandConfig.Name}, flags...), values[index:]...)
+
+	return parent, values, nil
+}
+
 // SetExecutableName sets the executable name of the registry.
 func (cr *CommandRegistry) SetExecutableName(name string) *CommandRegistry {
 
 	if _name := removeWhitespaces(name); _name == "" {
-		fmt.Printf("Error: executable name must be a non-empty string.\n")
-		os.Exit(0)
+		cr.fail("executable name must be a non-empty string")
 	} else {
 		cr.Executable = _name
 	}
@@ -180,6 +798,171 @@ func (cr *CommandRegistry) SetDescription(desc string) *CommandRegistry {
 	return cr
 }
 
+// SetExitCodes sets the exit codes of the process used by `Parse` for usage errors
+// (unknown command, missing argument etc.) and failures (action or configuration errors).
+// The process always exits with `ExitCodeSuccess` when usage or version information is displayed.
+// An action function can choose its own exit code by returning an `*ExitError` value.
+func (cr *CommandRegistry) SetExitCodes(usageCode int, failureCode int) *CommandRegistry {
+
+	cr.UsageExitCode = usageCode
+	cr.FailureExitCode = failureCode
+
+	return cr
+}
+
+// SetSuggestionDistance sets the maximum edit distance between an unknown command or flag name
+// and a registered name to suggest it ("Did you mean this?"). A value of `0` disables the suggestions.
+func (cr *CommandRegistry) SetSuggestionDistance(distance int) *CommandRegistry {
+
+	cr.SuggestionDistance = distance
+
+	return cr
+}
+
+// SetSortHelp sets whether the commands and flags are displayed in the alphabetical order in the usage information.
+// By default, they are displayed in the registration order. Built-in commands and flags are always displayed last.
+func (cr *CommandRegistry) SetSortHelp(sortHelp bool) *CommandRegistry {
+
+	cr.SortHelp = sortHelp
+
+	return cr
+}
+
+// SetEnvPrefix binds every flag to an environment variable with the prefix, the names of the command
+// and its parent commands and the long-name of the flag in the uppercase separated by `_`, for example
+// `REACTOR_CREATE_DIR` for the `--dir` flag of the `create` command with the `REACTOR` prefix
+// (`REACTOR_DIR` for a flag of the root-command). An empty prefix disables the automatic binding.
+func (cr *CommandRegistry) SetEnvPrefix(prefix string) *CommandRegistry {
+	cr.EnvPrefix = prefix
+
+	return cr
+}
+
+// Use registers middleware which wraps the action functions of all commands with their pre-run and post-run hooks.
+// The first registered middleware is the outermost, it is executed first and returns last.
+func (cr *CommandRegistry) Use(middlewares ...Middleware) *CommandRegistry {
+	cr.Middlewares = append(cr.Middlewares, middlewares...)
+
+	return cr
+}
+
+// SetOut sets the writer of the usage information, the version and the completion scripts (`os.Stdout` by default).
+func (cr *CommandRegistry) SetOut(w io.Writer) *CommandRegistry {
+	cr.Stdout = w
+
+	return cr
+}
+
+// SetErr sets the writer of the error messages displayed by `Parse` and the registration methods (`os.Stderr` by default).
+func (cr *CommandRegistry) SetErr(w io.Writer) *CommandRegistry {
+	cr.Stderr = w
+
+	return cr
+}
+
+// get the writer of the usage information
+func (cr *CommandRegistry) stdout() io.Writer {
+	if cr.Stdout == nil {
+		return os.Stdout
+	}
+
+	return cr.Stdout
+}
+
+// get the writer of the error messages
+func (cr *CommandRegistry) stderr() io.Writer {
+	if cr.Stderr == nil {
+		return os.Stderr
+	}
+
+	return cr.Stderr
+}
+
+// sort commands for the usage information (registration order or alphabetical order)
+// with built-in `help` and `version` commands at the end
+func (cr *CommandRegistry) sortCommands(commands map[string]*Command) []*Command {
+	list := make([]*Command, 0, len(commands))
+	for _, command := range commands {
+		list = append(list, command)
+	}
+
+	// check if a command is registered automatically
+	isBuiltIn := func(c *Command) bool {
+		return c.parent != nil && c.parent.IsRoot && (c.Name() == helpCommandName || c.Name() == versionCommandName)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if isBuiltIn(list[i]) != isBuiltIn(list[j]) {
+			return isBuiltIn(list[j])
+		}
+
+		if cr.SortHelp {
+			return list[i].Name() < list[j].Name()
+		}
+
+		return list[i].order < list[j].order
+	})
+
+	return list
+}
+
+// sort flags for the usage information (registration order or alphabetical order)
+// with built-in `--help` and `--version` flags at the end
+func (cr *CommandRegistry) sortFlags(flags map[string]*Flag) []*Flag {
+	list := make([]*Flag, 0, len(flags))
+	for _, flag := range flags {
+		list = append(list, flag)
+	}
+
+	// check if a flag is registered automatically
+	isBuiltIn := func(flag *Flag) bool {
+		return flag.ClpFlag.Name == helpFlagName || flag.ClpFlag.Name == versionFlagName
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if isBuiltIn(list[i]) != isBuiltIn(list[j]) {
+			return isBuiltIn(list[j])
+		}
+
+		if cr.SortHelp {
+			return list[i].ClpFlag.Name < list[j].ClpFlag.Name
+		}
+
+		return list[i].order < list[j].order
+	})
+
+	return list
+}
+
+// display an error message and exit the process with the failure exit code
+func (cr *CommandRegistry) fail(format string, a ...interface{}) {
+	fmt.Fprintf(cr.stderr(), "Error: %s.\n", fmt.Sprintf(format, a...))
+	os.Exit(cr.FailureExitCode)
+}
+
+// get exit code of the process for an error returned by `run`
+func (cr *CommandRegistry) exitCode(err error) int {
+
+	// exit code chosen by the action function
+	var exitError *ExitError
+	if errors.As(err, &exitError) {
+		return exitError.Code
+	}
+
+	// a missing action function is a configuration error
+	if errors.Is(err, ErrMissingAction) {
+		return cr.FailureExitCode
+	}
+
+	// usage error
+	var parseError *ParseError
+	if errors.As(err, &parseError) {
+		return cr.UsageExitCode
+	}
+
+	return cr.FailureExitCode
+}
+
 // SetEventListener registers a callback function with the registry.
 // This function is executed with an event name when the user uses `--help` or `--version` flag.
 // If this function is already registered, it won't get registered again.
@@ -203,8 +986,7 @@ func (cr *CommandRegistry) Register(name interface{}) *Command {
 
 	// print error if `name` is not a string
 	if _, ok := name.(string); name != nil && !ok {
-		fmt.Printf("Error: value of the command must be a string.\n")
-		os.Exit(0)
+		cr.fail("value of the command must be a string")
 	}
 
 	// if `name` is `nil`, it is a root-command
@@ -239,11 +1021,25 @@ func (cr *CommandRegistry) Register(name interface{}) *Command {
 	return c
 }
 
-// Parse parses the command-line arguments and executes the action function registered with the command.
-// If there is an usage-error while parsing the command-line arguments,
-// it will display a message in the console without returning an error.
-// If osArgs is `nil`, Parse uses arguments received from `os.Args[1:]`.
-func (cr *CommandRegistry) Parse(osArgs []string) {
+// reset values captured by `clapper` during the previous parse
+// and register persistent flags of the parent commands with sub-commands
+func (cr *CommandRegistry) prepareCommands() {
+	for _, command := range cr.allCommands() {
+		command.registerInheritedFlags()
+
+		for _, clpFlag := range command.clpCommandConfig.Flags {
+			clpFlag.Value = ""
+		}
+
+		for _, clpArg := range command.clpCommandConfig.Args {
+			clpArg.Value = ""
+		}
+	}
+}
+
+// run parses the command-line arguments and executes the action function registered with the command.
+// The returned boolean value is `true` only when the action function is executed.
+func (cr *CommandRegistry) run(osArgs []string) (bool, error) {
 
 	// get command-line arguments
 	_osArgs := osArgs
@@ -257,177 +1053,515 @@ func (cr *CommandRegistry) Parse(osArgs []string) {
 	// a store for flag values in `ArgFlag` format
 	flagValues := make(map[string]FlagValue)
 
-	/*---------------------------*/
+	/*---------------------------*/
+
+	// clear values of the previous parse (`clapper` does not reset them)
+	cr.prepareCommands()
+
+	// print completion candidates of the partial command-line arguments
+	if cr.completion && len(_osArgs) > 0 && _osArgs[0] == completeCommandName {
+		return false, cr.writeCompletions(cr.stdout(), _osArgs[1:])
+	}
+
+	// find the parent command of a nested sub-command
+	parent, values, err := cr.findCommand(_osArgs)
+	if err != nil {
+		return false, err
+	}
+
+	// `clapper` registry and commands of the parent command
+	clpRegistry, commands := cr.registry, cr.Commands
+	if parent != nil {
+		clpRegistry, commands = parent.clpRegistry, parent.Commands
+	}
+
+	// collect values of the repeatable flags (`clapper` only keeps the last value of a flag)
+	values, repeatedValues, err := parsedCommand(commands, values).collectRepeatableFlags(values)
+	if err != nil {
+		return false, err
+	}
+
+	// parse arguments with `clapper` and get the result.
+	// `result` is a struct of type `*clapper.CommandConfig`
+	result, err := clpRegistry.Parse(values)
+
+	/*---------------------------*/
+
+	// check for errors
+	if err != nil {
+		switch err.(type) {
+
+		// unknown command (of the root-command)
+		case clapper.ErrorUnknownCommand:
+			errorUnknownCommand := err.(clapper.ErrorUnknownCommand)
+			return false, cr.unknownCommandError(cr.Commands[rootCommandName], errorUnknownCommand.Name)
+
+		// unknown flag
+		case clapper.ErrorUnknownFlag:
+			return false, cr.unknownFlagError(parsedCommand(commands, values), err.(clapper.ErrorUnknownFlag))
+
+		// unsupported flag
+		case clapper.ErrorUnsupportedFlag:
+			errorUnsupportedFlag := err.(clapper.ErrorUnsupportedFlag)
+			return false, newParseError(ErrUnsupportedFlag, errorUnsupportedFlag.Name, err, "%s is not a supported flag", errorUnsupportedFlag.Name)
+
+		// other error
+		default:
+			return false, err
+		}
+	}
+
+	/*---------------------------*/
+
+	// get command configuration from the registry
+	command := commands[result.Name]
+
+	/*---------------------------*/
+
+	// if `help` command is provided, display usage of the root-command or the given command
+	if parent == nil && result.Name == helpCommandName {
+		var path []string
+		if value := result.Args[helpCommandArgName].Value; value != "" {
+			path = strings.Split(value, ",")
+		}
+
+		helpCommand, err := cr.lookupCommand(path)
+		if err != nil {
+			return false, err
+		}
+
+		cr.PrintHelp(helpCommand)
+		return false, nil
+	}
+
+	// if `--help` or `-h` flag is provided, display usage of the command
+	if result.Flags[helpFlagName].Value == "true" {
+		cr.PrintHelp(command)
+		return false, nil
+	}
+
+	// if `version` command or `--version` flag is provided for the root-command, display version number
+	if (parent == nil && result.Name == versionCommandName) || (command.IsRoot && result.Flags[versionFlagName].Value == "true") {
+		cr.PrintVersion()
+		return false, nil
+	}
+
+	/*---------------------------*/
+
+	// check if action function is missing
+	if command.Action == nil && command.ActionE == nil {
+
+		// display usage of a command which only groups sub-commands
+		if !command.IsRoot && len(command.Commands) > 0 {
+			cr.PrintHelp(command)
+			return false, nil
+		}
+
+		// return error only for non-root-command
+		if !command.IsRoot {
+			name := command.Path()
+			return false, newParseError(ErrMissingAction, name, nil, "action function for the %s command is not registered", name)
+		}
+
+		return false, nil
+	}
+
+	/*---------------------------*/
+
+	// values of the arguments (`clapper` joins values of a variadic argument with a comma)
+	userArgValues := command.collectArguments(values)
+
+	// for each argument, validate the argument value
+	for name, arg := range command.Args {
+
+		// get final values (default-value if the user-value is missing)
+		values, source := userArgValues[name], SourceCommandLine
+		if len(values) == 0 {
+			source = SourceDefault
+			if len(arg.ClpArg.DefaultValue) > 0 {
+				values = []string{arg.ClpArg.DefaultValue}
+			}
+		}
+
+		/*------------*/
+
+		// number of values of a variadic argument must satisfy its arity
+		if arg.ClpArg.IsVariadic {
+			if count := len(values); count < arg.Arity.Min {
+				return false, newParseError(ErrMissingArgument, name, nil, "expected %s of the %s argument, got %d", arg.Arity, name, count)
+			} else if arg.Arity.Max >= 0 && count > arg.Arity.Max {
+				return false, newParseError(ErrTooManyArguments, name, nil, "expected %s of the %s argument, got %d", arg.Arity, name, count)
+			}
+		}
+
+		// if argument is required but value is missing, return an error
+		if arg.IsRequired && len(values) == 0 {
+			return false, newParseError(ErrMissingArgument, name, nil, "value of the %s argument can not be empty", name)
+		}
+
+		// values must be one of the choices
+		if !isChoice(arg.Choices, values...) {
+			return false, newParseError(ErrInvalidArgumentValue, name, nil, "value of the %s argument must be one of %s", name, strings.Join(arg.Choices, ", "))
+		}
+
+		/*------------*/
+
+		// convert values to the data type of the argument
+		typedValue, err := arg.convert(values)
+		if err != nil {
+			desc := dataTypeDescs[arg.DataType]
+			if arg.DataType == Custom {
+				desc = "a valid " + arg.CustomValue.Type()
+			}
+
+			return false, newParseError(ErrInvalidArgumentValue, name, err, "value of the %s argument must be %s", name, desc)
+		}
+
+		// validate the value (the default-value of a non-variadic argument is not validated,
+		// the values of a variadic argument are always validated even if there are no values)
+		if source != SourceDefault || arg.ClpArg.IsVariadic {
+			if err := validate(arg.Validators, typedValue); err != nil {
+				return false, newParseError(ErrInvalidArgumentValue, name, err, "value of the %s argument %v", name, err)
+			}
+		}
+
+		// save argument value inside `argValues`
+		argValues[name] = ArgValue{
+			Arg:        *arg,
+			Value:      strings.Join(values, ","),
+			Values:     values,
+			TypedValue: typedValue,
+			source:     source,
+		}
+	}
+
+	/*---------------------------*/
+
+	// flags of the command and persistent flags of the parent commands
+	flags := command.inheritedFlags()
+	for name, flag := range command.Flags {
+		flags[name] = flag
+	}
+
+	// values of the configuration file
+	cfg, err := cr.readConfig(flags, result)
+	if err != nil {
+		return false, err
+	}
+
+	// for each flag, validate the flag value
+	for name, flag := range flags {
+
+		// get user-values of the flag from the command-line (values of a repeatable flag are collected separately)
+		userValues := repeatedValues[name]
+		if value := result.Flags[name].Value; !isRepeatableDataType(flag.DataType) && len(value) > 0 {
+			userValues = []string{value}
+		}
+
+		// source of the user-values (and its description for the error messages)
+		source, origin := SourceCommandLine, fmt.Sprintf("--%s flag", name)
+
+		// get user-value from an environment variable if the flag is not provided
+		if len(userValues) == 0 {
+			if envName, envValue, ok := flag.lookupEnv(); ok {
+				userValues = []string{envValue}
+				source, origin = SourceEnv, fmt.Sprintf("%s environment variable", envName)
+			}
+		}
+
+		// get user-values from the configuration file if the flag is not provided
+		if len(userValues) == 0 {
+			if key, configValues, ok := cfg.lookup(flag); ok {
+				userValues = configValues
+				source, origin = SourceConfig, fmt.Sprintf("%s key of the config file", key)
+			}
+		}
+
+		// default-value is used if the flag is not provided
+		if len(userValues) == 0 {
+			source = SourceDefault
+		}
+
+		/*------------*/
+
+		// values of a repeatable flag
+		if isRepeatableDataType(flag.DataType) {
+
+			// if flag is required but value is missing (or all the values are empty), return an error
+			if flag.IsRequired && len(splitValues(userValues, flag.Delimiter)) == 0 {
+				return false, newParseError(ErrMissingFlag, name, nil, "value of the --%s flag can not be empty", name)
+			}
+
+			// values must be one of the choices
+			if !isChoice(flag.Choices, splitValues(userValues, flag.Delimiter)...) {
+				return false, newParseError(ErrInvalidFlagValue, name, nil, "values of the %s must be one of %s", origin, strings.Join(flag.Choices, ", "))
+			}
+
+			// get final value (an optional flag without a default-value has no values)
+			safeValue := copyValues(flag.DefaultValue)
+			if safeValue == nil {
+				safeValue = zeroValue(flag.DataType)
+			}
+
+			if len(userValues) > 0 {
+				if safeValue, err = convertValues(flag.DataType, userValues, flag.Delimiter); err != nil {
+
+					// a malformed pair of a map flag is displayed in the error message
+					if flag.DataType == StringMap {
+						return false, newParseError(ErrInvalidFlagValue, name, err, "value of the %s must be %s (%v)", origin, dataTypeDescs[flag.DataType], err)
+					}
+
+					return false, newParseError(ErrInvalidFlagValue, name, err, "value of the %s must be %s", origin, dataTypeDescs[flag.DataType])
+				}
+			}
+
+			// validate the final values (even if there are no values)
+			if err := validate(flag.Validators, safeValue); err != nil {
+				return false, newParseError(ErrInvalidFlagValue, name, err, "value of the %s %v", origin, err)
+			}
+
+			flagValues[name] = FlagValue{
+				Flag:   *flag,
+				Value:  safeValue,
+				source: source,
+			}
+
+			continue
+		}
+
+		// get final value
+		value := result.Flags[name].DefaultValue
+		if len(userValues) > 0 {
+			value = userValues[0]
+		}
+
+		/*------------*/
+
+		// if flag is required but value is missing, return an error
+		if flag.IsRequired && len(value) == 0 {
+			return false, newParseError(ErrMissingFlag, name, nil, "value of the --%s flag can not be empty", name)
+		}
+
+		/*------------*/
+
+		// value must be one of the choices
+		if !isChoice(flag.Choices, value) {
+			return false, newParseError(ErrInvalidFlagValue, name, nil, "value of the %s must be one of %s", origin, strings.Join(flag.Choices, ", "))
+		}
+
+		/*------------*/
 
-	// parse arguments with `clapper` and get the result.
-	// `result` is a struct of type `*clapper.CommandConfig`
-	result, err := cr.registry.Parse(_osArgs)
+		// set value of a custom flag (the `Value` implementation is passed to the action function)
+		if flag.DataType == Custom {
+			// (an optional flag without a value keeps its current value)
+			if len(value) > 0 {
+				if err := flag.CustomValue.Set(value); err != nil {
+					return false, newParseError(ErrInvalidFlagValue, name, err, "value of the %s must be a valid %s", origin, flag.CustomValue.Type())
+				}
+			}
 
-	/*---------------------------*/
+			// validate the value (the default-value is not validated)
+			if source != SourceDefault {
+				if err := validate(flag.Validators, flag.CustomValue); err != nil {
+					return false, newParseError(ErrInvalidFlagValue, name, err, "value of the %s %v", origin, err)
+				}
+			}
 
-	// check for errors
-	if err != nil {
-		switch err.(type) {
+			flagValues[name] = FlagValue{
+				Flag:   *flag,
+				Value:  flag.CustomValue,
+				source: source,
+			}
 
-		// unknown command
-		case clapper.ErrorUnknownCommand:
-			errorUnknownCommand := err.(clapper.ErrorUnknownCommand)
-			fmt.Printf("Error: %s is not a valid command.\n", errorUnknownCommand.Name)
+			continue
+		}
 
-		// unknown flag
-		case clapper.ErrorUnknownFlag:
-			errorUnknownFlag := err.(clapper.ErrorUnknownFlag)
-			fmt.Printf("Error: %s is not a valid flag.\n", errorUnknownFlag.Name)
+		// an optional flag without a default-value has the zero value of its data type
+		if len(value) == 0 {
+			flagValues[name] = FlagValue{
+				Flag:   *flag,
+				Value:  zeroValue(flag.DataType),
+				source: source,
+			}
 
-		// unsupported flag
-		case clapper.ErrorUnsupportedFlag:
-			errorUnsupportedFlag := err.(clapper.ErrorUnsupportedFlag)
-			fmt.Printf("Error: %s is not a supported flag.\n", errorUnsupportedFlag.Name)
+			continue
+		}
 
-		// other error
-		default:
-			fmt.Printf("Error: %s.\n", err)
+		// convert `value` to an appropriate data type
+		safeValue, err := convertValue(flag.DataType, value)
+		if err != nil {
+			return false, newParseError(ErrInvalidFlagValue, name, err, "value of the %s must be %s", origin, dataTypeDescs[flag.DataType])
+		}
+
+		// validate the value (the default-value is not validated)
+		if source != SourceDefault {
+			if err := validate(flag.Validators, safeValue); err != nil {
+				return false, newParseError(ErrInvalidFlagValue, name, err, "value of the %s %v", origin, err)
+			}
 		}
 
-		// exit process
-		os.Exit(0)
+		/*------------*/
+
+		// save flag display-value inside `argValues`
+		flagValues[name] = FlagValue{
+			Flag:   *flag,
+			Value:  safeValue,
+			source: source,
+		}
 	}
 
 	/*---------------------------*/
 
-	// get command configuration from the registry
-	command := cr.Commands[result.Name]
+	// check the constraints between the flags
+	for _, group := range command.FlagGroups {
+		if err := group.check(flagValues); err != nil {
+			return false, err
+		}
+	}
+
+	// check the validation rules (in the registration order)
+	for _, rule := range command.Rules {
+		if err := checkRule(rule, argValues, flagValues); err != nil {
+			return false, err
+		}
+	}
 
 	/*---------------------------*/
 
-	// if `help` command is provided, display usage of the root-command
-	if result.Name == helpCommandName {
-		cr.PrintHelp(cr.Commands[rootCommandName]) // usage of the root-command
-		os.Exit(0)
+	// execute action function with the hooks and the middleware. The same context is passed to all of them,
+	// it is cancelled on SIGINT or SIGTERM unless the command only has an `Action` function (which can not receive it).
+	ctx := context.Background()
+	if cr.receivesContext(command) {
+		var cancel context.CancelFunc
+		ctx, cancel = signalContext()
+		defer cancel()
 	}
 
-	// if `--help` or `-h` flag is provided, display usage of the command
-	if result.Flags[helpFlagName].Value == "true" {
-		cr.PrintHelp(command)
-		os.Exit(0)
+	return true, cr.action(command)(ctx, argValues, flagValues)
+}
+
+// check if the action function, the hooks of the command and its parent commands or the middleware receive a context
+func (cr *CommandRegistry) receivesContext(command *Command) bool {
+	if command.ActionE != nil || len(cr.Middlewares) > 0 {
+		return true
 	}
 
-	// if `version` command or `--version` flag is provided for the root-command, display version number
-	if result.Name == versionCommandName || (command.IsRoot && result.Flags[versionFlagName].Value == "true") {
-		cr.PrintVersion()
-		os.Exit(0)
+	for c := command; c != nil; c = c.parent {
+		if c.PreRun != nil || c.PostRun != nil {
+			return true
+		}
 	}
 
-	/*---------------------------*/
+	return false
+}
 
-	// check if action function is missing
-	if command.Action == nil {
+// get the action function of a command wrapped with the pre-run and post-run hooks of the command
+// and its parent commands and the middleware of the registry
+func (cr *CommandRegistry) action(command *Command) ActionFunc {
 
-		// show error message only for non-root-command
-		if !command.IsRoot {
-			fmt.Printf("Error: action function for the %s command is not registered.\n", command.clpCommandConfig.Name)
+	// action function of the command
+	action := command.ActionE
+	if action == nil {
+		action = func(ctx context.Context, args map[string]ArgValue, flags map[string]FlagValue) error {
+			command.Action(args, flags)
+			return nil
 		}
-
-		os.Exit(0)
 	}
 
-	/*---------------------------*/
-
-	// for each argument, validate the argument value
-	for name, arg := range command.Args {
+	// the command and its parent commands (from the root-command)
+	commands := make([]*Command, 0)
+	for c := command; c != nil; c = c.parent {
+		commands = append([]*Command{c}, commands...)
+	}
 
-		// get default-value and user-value of the argument from the `result`
-		defaultValue := result.Args[name].DefaultValue
-		userValue := result.Args[name].Value
+	run := func(ctx context.Context, args map[string]ArgValue, flags map[string]FlagValue) error {
 
-		// get final value
-		value := userValue
-		if len(userValue) == 0 {
-			value = defaultValue
+		// pre-run hooks of the parent commands run first
+		for _, c := range commands {
+			if c.PreRun != nil {
+				if err := c.PreRun(ctx, args, flags); err != nil {
+					return err
+				}
+			}
 		}
 
-		/*------------*/
-
-		// if argument is required but value is missing, display an error message and exit the process
-		if arg.IsRequired && len(value) == 0 {
-			fmt.Printf("Error: value of the %s argument can not be empty.\n", name)
-			os.Exit(0)
+		if err := action(ctx, args, flags); err != nil {
+			return err
 		}
 
-		// save flag display-value inside `argValues`
-		argValues[name] = ArgValue{
-			Arg:   *arg,
-			Value: value,
+		// post-run hooks of the parent commands run last
+		for index := len(commands) - 1; index >= 0; index-- {
+			if c := commands[index]; c.PostRun != nil {
+				if err := c.PostRun(ctx, args, flags); err != nil {
+					return err
+				}
+			}
 		}
+
+		return nil
 	}
 
-	/*---------------------------*/
+	// the first middleware is the outermost
+	for index := len(cr.Middlewares) - 1; index >= 0; index-- {
+		run = cr.Middlewares[index](run)
+	}
 
-	// for each flag, validate the flag value
-	for name, flag := range command.Flags {
+	return run
+}
 
-		// get default-value and user-value of the flag from the `result`
-		defaultValue := result.Flags[name].DefaultValue
-		userValue := result.Flags[name].Value
+// ParseE parses the command-line arguments and executes the action function registered with the command.
+// Unlike `Parse`, it does not exit the process. If there is an usage-error while parsing the command-line arguments,
+// it returns a `*ParseError` value that matches one of the `Err*` values with `errors.Is`.
+// The error returned by an action function registered with `SetActionE` is returned as it is.
+// If osArgs is `nil`, ParseE uses arguments received from `os.Args[1:]`.
+func (cr *CommandRegistry) ParseE(osArgs []string) error {
+	_, err := cr.run(osArgs)
+	return err
+}
 
-		// get final value
-		value := userValue
-		if len(userValue) == 0 {
-			value = defaultValue
-		}
+// Parse parses the command-line arguments and executes the action function registered with the command.
+// If there is an usage-error while parsing the command-line arguments,
+// it will display a message in the console and exit the process with the usage exit code.
+// If osArgs is `nil`, Parse uses arguments received from `os.Args[1:]`.
+func (cr *CommandRegistry) Parse(osArgs []string) {
 
-		/*------------*/
+	// parse arguments and execute the action function
+	executed, err := cr.run(osArgs)
 
-		// if flag is required but value is missing, display an error message and exit the process
-		if flag.IsRequired && len(value) == 0 {
-			fmt.Printf("Error: value of the --%s flag can not be empty.\n", name)
-			os.Exit(0)
+	// display the error message and exit process with an appropriate exit code
+	if err != nil {
+		var exitError *ExitError
+		if !errors.As(err, &exitError) || exitError.Err != nil {
+			fmt.Fprintf(cr.stderr(), "Error: %s.\n", err)
 		}
 
-		/*------------*/
-
-		// convert `value` to an appropriate data type
-		var safeValue interface{}
-		switch flag.DataType {
-		case Bool:
-			if value == "true" {
-				safeValue = true
-			} else {
-				safeValue = false
-			}
-		case Int:
-			if _value, err := strconv.ParseInt(value, 10, 64); err == nil {
-				safeValue = int(_value)
-			} else {
-				fmt.Printf("Error: value of the --%s flag must be an integer.\n", name)
-				os.Exit(0)
+		// display similar command or flag names
+		var parseError *ParseError
+		if errors.As(err, &parseError) && len(parseError.Suggestions) > 0 {
+			fmt.Fprintf(cr.stderr(), "\nDid you mean this?\n")
+			for _, suggestion := range parseError.Suggestions {
+				fmt.Fprintf(cr.stderr(), "   %s\n", suggestion)
 			}
-		case String:
-			safeValue = value
 		}
 
-		/*------------*/
-
-		// save flag display-value inside `argValues`
-		flagValues[name] = FlagValue{
-			Flag:  *flag,
-			Value: safeValue,
-		}
+		os.Exit(cr.exitCode(err))
 	}
 
-	/*---------------------------*/
-
-	// execute action function
-	command.Action(argValues, flagValues)
-
+	// exit process if the action function is not executed
+	if !executed {
+		os.Exit(ExitCodeSuccess)
+	}
 }
 
 // NewCommandRegistry returns a new value of registry and registers the root-command
 // with a bare-minimum configuration to enable `--help` and `--version` command.
 func NewCommandRegistry() *CommandRegistry {
 	registery := &CommandRegistry{
-		registry: clapper.NewRegistry(),
-		Commands: make(map[string]*Command),
+		registry:           clapper.NewRegistry(),
+		Commands:           make(map[string]*Command),
+		UsageExitCode:      ExitCodeUsage,
+		FailureExitCode:    ExitCodeFailure,
+		SuggestionDistance: defaultSuggestionDistance,
 	}
 
 	// add root-command automatically
@@ -437,7 +1571,7 @@ func NewCommandRegistry() *CommandRegistry {
 	registery.Register(versionCommandName).SetDescription(versionCommandDesc).SetShortDescription(versionCommandShortDesc)
 
 	// add help command automatically
-	registery.Register(helpCommandName).SetDescription(helpCommandDesc).SetShortDescription(helpCommandShortDesc)
+	registery.Register(helpCommandName).SetDescription(helpCommandDesc).SetShortDescription(helpCommandShortDesc).AddArgument(helpCommandArgName+"...", helpCommandArgDesc, "")
 
 	return registery
 }
@@ -456,7 +1590,7 @@ func (cr *CommandRegistry) PrintVersion() {
 		panic(err)
 	} else {
 		// compile and output template result
-		tmpl.Execute(os.Stdout, templateData)
+		tmpl.Execute(cr.stdout(), templateData)
 	}
 
 	/*----------------*/
@@ -473,13 +1607,8 @@ func (cr *CommandRegistry) PrintHelp(c *Command) {
 	// get executable name
 	exeName := cr.Executable
 
-	// commands (without root-command)
-	commands := make(map[string]*Command)
-	for name, command := range cr.Commands {
-		if !command.IsRoot {
-			commands[name] = command
-		}
-	}
+	// sub-commands (without root-command)
+	commands := cr.sortCommands(cr.subCommands(c))
 
 	// arguments (ordered list)
 	arguments := make([]*Arg, 0)
@@ -487,6 +1616,15 @@ func (cr *CommandRegistry) PrintHelp(c *Command) {
 		arguments = append(arguments, c.Args[name])
 	}
 
+	// persistent flags of the parent commands (with short-names accepted by the command)
+	c.registerInheritedFlags()
+	globalFlags := make(map[string]*Flag)
+	for name, flag := range c.inheritedFlags() {
+		globalFlag := *flag
+		globalFlag.ClpFlag = c.clpCommandConfig.Flags[name]
+		globalFlags[name] = &globalFlag
+	}
+
 	// template data
 	templateData := struct {
 		CliDesc       string
@@ -494,26 +1632,32 @@ func (cr *CommandRegistry) PrintHelp(c *Command) {
 		IsRootCommand bool
 		Desc          string
 		Args          []*Arg
-		Flags         map[string]*Flag
-		Commands      map[string]*Command
+		Flags         []*Flag
+		GlobalFlags   []*Flag
+		Commands      []*Command
 		Command       string
+		Constraints   []FlagGroup
 	}{
 		CliDesc:       cr.Desc,
 		Executable:    exeName,
 		IsRootCommand: c.IsRoot,
 		Desc:          c.Desc,
 		Args:          arguments,
-		Flags:         c.Flags,
+		Flags:         cr.sortFlags(c.Flags),
+		GlobalFlags:   cr.sortFlags(globalFlags),
 		Commands:      commands,
-		Command:       c.clpCommandConfig.Name,
+		Command:       c.Path(),
+		Constraints:   c.FlagGroups,
 	}
 
-	// parse help template
-	if tmpl, err := template.New("help").Parse(usageTemplate); err != nil {
+	// parse help template (and the template of flags)
+	if tmpl, err := template.New("help").Funcs(template.FuncMap{"join": strings.Join}).Parse(usageTemplate); err != nil {
+		panic(err)
+	} else if _, err := tmpl.New("flags").Parse(flagsTemplate); err != nil {
 		panic(err)
 	} else {
 		// compile and output template result
-		tmpl.Execute(os.Stdout, templateData)
+		tmpl.Execute(cr.stdout(), templateData)
 	}
 
 	/*----------------*/
@@ -529,12 +1673,32 @@ var DefaultCommandRegistry = NewCommandRegistry()
 
 /*---------------------*/
 
+// ActionFunc is an action function that receives a context and returns an error.
+// The context is cancelled when the process receives an interrupt (SIGINT) or a termination (SIGTERM) signal.
+type ActionFunc func(ctx context.Context, args map[string]ArgValue, flags map[string]FlagValue) error
+
+// Middleware wraps the action function of a command (with its pre-run and post-run hooks), for example to time the command.
+// It returns an action function which calls the `next` action function (see `CommandRegistry.Use`).
+type Middleware func(next ActionFunc) ActionFunc
+
 // Command holds the configuration of a command.
 type Command struct {
 
+	// registration order of the command
+	order int
+
+	// registry of the command
+	commandRegistry *CommandRegistry
+
 	// command configuration of the `clapper`
 	clpCommandConfig *clapper.CommandConfig
 
+	// registry to hold `clapper` command configurations of the sub-commands
+	clpRegistry clapper.Registry
+
+	// parent command (`nil` for the root-command)
+	parent *Command
+
 	// description of the command
 	Desc string
 
@@ -550,8 +1714,139 @@ type Command struct {
 	// flags to parse from the command-line arguments
 	Flags map[string]*Flag
 
+	// flags which are also accepted by the sub-commands (also stored in `Flags`)
+	PersistentFlags map[string]*Flag
+
+	// registered sub-commands (top-level commands are stored in the `CommandRegistry`)
+	Commands map[string]*Command
+
+	// alternative names of the command
+	Aliases []string
+
+	// constraints between the flags of the command
+	FlagGroups []FlagGroup
+
+	// validation rules of the argument and flag values (see `AddRule`)
+	Rules []RuleFunc
+
 	// Action function
 	Action func(map[string]ArgValue, map[string]FlagValue)
+
+	// Action function which returns an error (takes precedence over `Action`)
+	ActionE ActionFunc
+
+	// function executed before the action function of the command and its sub-commands (see `SetPreRun`)
+	PreRun ActionFunc
+
+	// function executed after the action function of the command and its sub-commands (see `SetPostRun`)
+	PostRun ActionFunc
+}
+
+// Register registers a sub-command under the command and adds `--help` flag automatically.
+// Sub-commands can be nested to any depth, for example `$ mytool cluster node add <name>`.
+// If the sub-command is already registered, it returns the registered `*Command` object.
+// For the root-command, it works the same as `CommandRegistry.Register`.
+func (c *Command) Register(name string) *Command {
+
+	// sub-commands of the root-command are top-level commands
+	if c.IsRoot {
+		return c.commandRegistry.Register(name)
+	}
+
+	// (replace all whitespaces)
+	_name := removeWhitespaces(name) // remove all whitespaces
+
+	// name of a sub-command can not be empty
+	if _name == "" {
+		c.commandRegistry.fail("name of the sub-command of the %s command must be a non-empty string", c.Path())
+	}
+
+	/*---------------------------*/
+
+	// name of the sub-command should not be an alias of another sub-command
+	if command := aliasOwner(c.Commands, _name); command != nil {
+		c.commandRegistry.fail("%s command conflicts with an alias of the %s command", _name, command.Path())
+	}
+
+	// register sub-command with clapper
+	clpCommandConfig, exists := c.clpRegistry.Register(_name)
+
+	// if sub-command is already registered, return
+	if exists {
+		return c.Commands[clpCommandConfig.Name]
+	}
+
+	// create a command config and register it with the command
+	subCommand := c.commandRegistry.newCommand(c, clpCommandConfig)
+	c.Commands[clpCommandConfig.Name] = subCommand
+
+	/*---------------------------*/
+
+	// add help flag (to print usage of the command with --help flag)
+	subCommand.AddFlag(fmt.Sprintf("%s,%s", helpFlagName, helpFlagShortName), helpFlagDesc, Bool, nil)
+
+	return subCommand
+}
+
+// Name returns the name of the command (an empty string for the root-command).
+func (c *Command) Name() string {
+	return c.clpCommandConfig.Name
+}
+
+// Path returns the names of the parent commands and the command separated by a space (e.g. `cluster node add`).
+// It returns an empty string for the root-command.
+func (c *Command) Path() string {
+	if c.IsRoot {
+		return rootCommandName
+	}
+
+	if c.parent == nil || c.parent.IsRoot {
+		return c.clpCommandConfig.Name
+	}
+
+	return c.parent.Path() + " " + c.clpCommandConfig.Name
+}
+
+// AddAlias registers alternative names of the command, for example `AddAlias("rm", "del")` for the `remove` command.
+// If an alias conflicts with the name or an alias of another command at the same level, it displays an error and exits the process.
+// The root-command can not have aliases.
+func (c *Command) AddAlias(aliases ...string) *Command {
+
+	// root-command is executed without a name
+	if c.IsRoot {
+		c.commandRegistry.fail("aliases can not be registered for the root-command")
+	}
+
+	// other commands at the same level
+	siblings := c.commandRegistry.subCommands(c.parent)
+
+	for _, alias := range aliases {
+
+		// (replace all whitespaces)
+		_alias := removeWhitespaces(alias) // remove all whitespaces
+
+		// alias should not be empty
+		if _alias == "" {
+			c.commandRegistry.fail("alias of the %s command must be a non-empty string", c.Path())
+		}
+
+		// skip if alias is already registered with the command
+		if command, ok := c.commandRegistry.findSubCommand(c.parent, _alias); ok && command == c {
+			continue
+		}
+
+		// alias should not conflict with other commands
+		if _, ok := siblings[_alias]; ok {
+			c.commandRegistry.fail("alias %s of the %s command conflicts with another command", _alias, c.Path())
+		}
+		if command := aliasOwner(siblings, _alias); command != nil {
+			c.commandRegistry.fail("alias %s of the %s command conflicts with an alias of the %s command", _alias, c.Path(), command.Path())
+		}
+
+		c.Aliases = append(c.Aliases, _alias)
+	}
+
+	return c
 }
 
 // SetDescription sets the description for a command.
@@ -572,13 +1867,62 @@ func (c *Command) SetShortDescription(shortDesc string) *Command {
 // When the defaultValue is an empty string, a user needs to provide a value for this argument.
 // If an argument name ends with `...`, it is an variadic argument.
 func (c *Command) AddArgument(name string, desc string, defaultValue string) *Command {
+	c.addArgument(name, desc, String, defaultValue)
+
+	return c
+}
+
+// AddTypedArgument registers an argument with a data type for a command, for example `commando.Int`.
+// The data type can be `String`, `Int`, `Float`, `Uint`, `Int64` or `Duration` and the value of the argument
+// is converted to the Go type of the data type (a slice for a variadic argument), see `ArgValue.TypedValue`.
+// If the defaultValue argument is `nil`, a user needs to provide a value for this argument.
+func (c *Command) AddTypedArgument(name string, desc string, dataType int, defaultValue interface{}) *Command {
+
+	// check the data type
+	if _, ok := dataTypeGoTypes[dataType]; !ok {
+		c.commandRegistry.fail("invalid data type provided for the %s argument", name)
+	}
+
+	// check for correct data type of `defaultValue`
+	var _defaultValue string
+	if defaultValue != nil {
+		if !isDataTypeValue(dataType, defaultValue) {
+			c.commandRegistry.fail("value of the %s argument must be %s or nil", name, dataTypeGoNames[dataType])
+		}
+
+		_defaultValue = fmt.Sprintf("%v", defaultValue)
+	}
+
+	c.addArgument(name, desc, dataType, _defaultValue)
+
+	return c
+}
+
+// AddArgumentValue registers an argument with a custom data type for a command, for example an IP address.
+// The `Set` method of the value is called with the value provided by the user (or the default-value) and
+// the value is passed to the action function as `ArgValue.TypedValue`. The current value of the value (`String()`)
+// is the default-value of the argument. If it is empty, the argument is required. It can not be a variadic argument.
+func (c *Command) AddArgumentValue(name string, desc string, value Value) *Command {
+	if value == nil || strings.HasSuffix(name, "...") {
+		c.commandRegistry.fail("%s argument must be a non-variadic argument with a commando.Value", name)
+	}
+
+	if arg := c.addArgument(name, desc, Custom, value.String()); arg.CustomValue == nil {
+		arg.CustomValue = value
+	}
+
+	return c
+}
+
+// register an argument for the command and return the registered argument
+func (c *Command) addArgument(name string, desc string, dataType int, defaultValue string) *Arg {
 
 	// register the argument with clapper
 	clpArg, exists := c.clpCommandConfig.AddArg(name, defaultValue)
 
 	// if argument is already registered, return
 	if exists {
-		return c
+		return c.Args[clpArg.Name]
 	}
 
 	/*---------------------------*/
@@ -587,24 +1931,172 @@ func (c *Command) AddArgument(name string, desc string, defaultValue string) *Co
 	arg := &Arg{
 		ClpArg:     clpArg,
 		Desc:       trimWhitespaces(desc),                    // trim whitespaces
-		IsRequired: defaultValue == "" && !clpArg.IsVariadic, // variadic arguments are always optional
+		DataType:   dataType,                                 // data type of the value
+		IsRequired: defaultValue == "" && !clpArg.IsVariadic, // variadic arguments are optional by default
+		Arity:      AtLeast(0),                               // any number of values of a variadic argument
+	}
+
+	/*---------------------------*/
+
+	// register the argument with the command
+	c.Args[clpArg.Name] = arg
+
+	/*---------------------------*/
+
+	return arg
+}
+
+// SetArgumentArity sets the minimum and maximum number of values of a variadic argument,
+// for example `SetArgumentArity("files...", commando.AtLeast(1))`. A variadic argument with
+// a minimum number of values is required. By default, a variadic argument takes any number of values.
+func (c *Command) SetArgumentArity(name string, arity Arity) *Command {
+	arg, ok := c.Args[strings.TrimSuffix(removeWhitespaces(name), "...")]
+	if !ok || !arg.ClpArg.IsVariadic {
+		c.commandRegistry.fail("%s argument is not a registered variadic argument", name)
+	}
+
+	if arity.Min < 0 || (arity.Max >= 0 && arity.Max < arity.Min) {
+		c.commandRegistry.fail("arity of the %s argument is invalid", name)
+	}
+
+	arg.Arity = arity
+	arg.IsRequired = arity.Min > 0
+
+	return c
+}
+
+// collect the values of the arguments from the command-line argument values parsed by `clapper`
+// (same as `clapper`, but values of a variadic argument are not joined)
+func (c *Command) collectArguments(values []string) map[string][]string {
+	argValues := make(map[string][]string)
+
+	// the first value is the name of a sub-command
+	if !c.IsRoot && len(values) > 0 {
+		values = values[1:]
+	}
+
+	// `clapper` splits flags by `=` (e.g. `--dir=./out` is `--dir ./out`)
+	tokens := make([]string, 0, len(values))
+	for _, value := range values {
+		if !isFlag(value) {
+			tokens = append(tokens, value)
+			continue
+		}
+
+		for _, part := range strings.Split(value, "=") {
+			if trimWhitespaces(part) != "" {
+				tokens = append(tokens, part)
+			}
+		}
+	}
+
+	// `clapper` stops at an empty value
+	argNames := c.clpCommandConfig.ArgNames
+	index := 0
+
+	for i := 0; i < len(tokens) && tokens[i] != ""; i++ {
+
+		// a flag with a value takes the next value
+		if isFlag(tokens[i]) {
+			if flag := c.findFlag(tokens[i]); flag != nil && !flag.ClpFlag.IsBoolean && i+1 < len(tokens) && tokens[i+1] != "" && !isFlag(tokens[i+1]) {
+				i++
+			}
+
+			continue
+		}
+
+		// the last variadic argument takes the remaining values (other values are ignored)
+		if index < len(argNames) {
+			argValues[argNames[index]] = append(argValues[argNames[index]], tokens[i])
+			index++
+		} else if len(argNames) > 0 && c.Args[argNames[len(argNames)-1]].ClpArg.IsVariadic {
+			name := argNames[len(argNames)-1]
+			argValues[name] = append(argValues[name], tokens[i])
+		}
+	}
+
+	return argValues
+}
+
+// AddFlag registers a flag for the command.
+// The flagNames argument should contain "long,short" flag names (e.g. "version,v").
+// If dataType argument is `commando.Bool` (boolean), then the defaultValue argument is ignored.
+// For non-boolean flags, if the defaultValue argument is `nil`, then the flag is required.
+func (c *Command) AddFlag(flagNames string, desc string, dataType int, defaultValue interface{}) *Command {
+	c.addFlag(flagNames, desc, dataType, defaultValue)
+
+	return c
+}
+
+// AddFlagValue registers a flag with a custom data type for the command, for example an IP address or a URL.
+// The flagNames and desc arguments are the same as `AddFlag`. The `Set` method of the value is called with the
+// value provided by the user (or the default-value) and the value is passed to the action function as `FlagValue.Value`.
+// The current value of the value (`String()`) is the default-value of the flag. If it is empty, the flag is required.
+func (c *Command) AddFlagValue(flagNames string, desc string, value Value) *Command {
+	c.addFlag(flagNames, desc, Custom, value)
+
+	return c
+}
+
+// AddPersistentFlag registers a flag for the command which is also accepted by all its sub-commands
+// (at any depth). A persistent flag of the root-command is accepted by all the commands.
+// The value of a persistent flag is passed to the action function of a sub-command with other flag values.
+// A sub-command can override a persistent flag by registering a flag with the same long-name.
+// The arguments are the same as `AddFlag`.
+func (c *Command) AddPersistentFlag(flagNames string, desc string, dataType int, defaultValue interface{}) *Command {
+	flag := c.addFlag(flagNames, desc, dataType, defaultValue)
+
+	c.PersistentFlags[flag.ClpFlag.Name] = flag
+
+	return c
+}
+
+// get persistent flags of the parent commands which are not overridden by the command
+func (c *Command) inheritedFlags() map[string]*Flag {
+	flags := make(map[string]*Flag)
+
+	for parent := c.parent; parent != nil; parent = parent.parent {
+		for name, flag := range parent.PersistentFlags {
+
+			// flags of the command and nearest parent commands take precedence
+			if _, ok := c.Flags[name]; ok {
+				continue
+			}
+			if _, ok := flags[name]; ok {
+				continue
+			}
+
+			flags[name] = flag
+		}
 	}
 
-	/*---------------------------*/
+	return flags
+}
 
-	// register the argument with the command
-	c.Args[clpArg.Name] = arg
+// register persistent flags of the parent commands with the `clapper` command configuration
+func (c *Command) registerInheritedFlags() {
+	for name, flag := range c.inheritedFlags() {
 
-	/*---------------------------*/
+		// short-name of an inherited flag is dropped if the command already uses it
+		shortName := flag.ClpFlag.ShortName
+		for _, clpFlag := range c.clpCommandConfig.Flags {
+			if clpFlag.Name != name && clpFlag.ShortName == shortName {
+				shortName = ""
+			}
+		}
 
-	return c
+		// an inverted flag is registered with the `no-` prefix
+		flagName := name
+		if flag.ClpFlag.IsInverted {
+			flagName = "no-" + name
+		}
+
+		c.clpCommandConfig.AddFlag(flagName, shortName, flag.ClpFlag.IsBoolean, flag.ClpFlag.DefaultValue)
+	}
 }
 
-// AddFlag registers a flag for the command.
-// The flagNames argument should contain "long,short" flag names (e.g. "version,v").
-// If dataType argument is `commando.Bool` (boolean), then the defaultValue argument is ignored.
-// For non-boolean flags, if the defaultValue argument is `nil`, then the flag is required.
-func (c *Command) AddFlag(flagNames string, desc string, dataType int, defaultValue interface{}) *Command {
+// register a flag for the command and return the registered flag
+func (c *Command) addFlag(flagNames string, desc string, dataType int, defaultValue interface{}) *Flag {
 
 	// (replace all whitespaces)
 	_flagNames := removeWhitespaces(flagNames)
@@ -634,27 +2126,47 @@ func (c *Command) AddFlag(flagNames string, desc string, dataType int, defaultVa
 	case Bool:
 		_defaultValue = "false"
 		_isRequired = false
-	case Int:
+	case Int, Float, Uint, Int64, Duration:
+		if defaultValue == nil {
+			_isRequired = true
+		} else {
+			// check if `defaultValue` has the type of the data type
+			if !isDataTypeValue(dataType, defaultValue) {
+				c.commandRegistry.fail("value of the --%s flag must be %s or nil", name, dataTypeGoNames[dataType])
+			}
+
+			_defaultValue = fmt.Sprintf("%v", defaultValue)
+			_isRequired = false
+		}
+	case StringSlice, IntSlice, StringMap:
 		if defaultValue == nil {
 			_isRequired = true
 		} else {
-			// check if `defaultValue` is a `int`
-			if _, ok := defaultValue.(int); !ok {
-				fmt.Printf("Error: value of the --%s flag must be an int or nil.\n", name)
-				os.Exit(0)
+			// check if `defaultValue` is a slice or a map of the data type
+			if !isDataTypeValue(dataType, defaultValue) {
+				c.commandRegistry.fail("value of the --%s flag must be %s or nil", name, dataTypeGoNames[dataType])
 			}
 
-			_defaultValue = fmt.Sprintf("%v", defaultValue.(int))
+			_defaultValue = formatValues(defaultValue, defaultFlagDelimiter)
 			_isRequired = false
 		}
+	case Custom:
+		// check if `defaultValue` is a `Value`
+		value, ok := defaultValue.(Value)
+		if !ok || value == nil {
+			c.commandRegistry.fail("value of the --%s flag must implement commando.Value", name)
+		}
+
+		// current value is the default-value
+		_defaultValue = value.String()
+		_isRequired = _defaultValue == ""
 	case String:
 		if defaultValue == nil {
 			_isRequired = true
 		} else {
 			// check if `defaultValue` is a `string`
 			if val, ok := defaultValue.(string); !ok {
-				fmt.Printf("Error: value of the --%s flag must be a string or nil.\n", name)
-				os.Exit(0)
+				c.commandRegistry.fail("value of the --%s flag must be a string or nil", name)
 			} else {
 				// check for empty string value
 				if removeWhitespaces(val) == "" {
@@ -666,8 +2178,7 @@ func (c *Command) AddFlag(flagNames string, desc string, dataType int, defaultVa
 			}
 		}
 	default:
-		fmt.Printf("Error: invalid data type provided for the --%s flag.\n", name)
-		os.Exit(0)
+		c.commandRegistry.fail("invalid data type provided for the --%s flag", name)
 	}
 
 	/*---------------------------*/
@@ -675,20 +2186,33 @@ func (c *Command) AddFlag(flagNames string, desc string, dataType int, defaultVa
 	// register the flag with clapper
 	clpFlag, exists := c.clpCommandConfig.AddFlag(name, shortName, dataType == Bool, _defaultValue)
 
-	// if argument is already registered, return
+	// if flag is already registered, return
 	if exists {
-		return c
+		return c.Flags[clpFlag.Name]
 	}
 
 	/*---------------------------*/
 
 	// create a flag object
 	flag := &Flag{
+		order:        c.commandRegistry.nextOrder(),
 		ClpFlag:      clpFlag,
 		Desc:         trimWhitespaces(desc), // trim whitespaces
 		DataType:     dataType,
 		DefaultValue: defaultValue,
 		IsRequired:   _isRequired,
+		command:      c,
+	}
+
+	// value of a custom flag is set by the `Value` implementation
+	if dataType == Custom {
+		flag.CustomValue = defaultValue.(Value)
+		flag.DefaultValue = _defaultValue
+	}
+
+	// values of a slice flag are also split by the default delimiter
+	if dataType == StringSlice || dataType == IntSlice {
+		flag.Delimiter = defaultFlagDelimiter
 	}
 
 	/*---------------------------*/
@@ -698,9 +2222,157 @@ func (c *Command) AddFlag(flagNames string, desc string, dataType int, defaultVa
 
 	/*---------------------------*/
 
+	return flag
+}
+
+// SetFlagDelimiter sets the delimiter which splits each value of a repeatable flag (`StringSlice`, `IntSlice` or `StringMap`)
+// into multiple values, for example `--tag=x,y` is the same as `--tag x --tag y` with the default delimiter (`,`).
+// The name argument is the long-name of the flag. An empty delimiter disables the splitting.
+// The values of a `StringMap` flag are not split by default.
+func (c *Command) SetFlagDelimiter(name string, delimiter string) *Command {
+	flag, ok := c.Flags[removeWhitespaces(name)]
+	if !ok || !isRepeatableDataType(flag.DataType) {
+		c.commandRegistry.fail("--%s flag is not a registered repeatable flag", name)
+	}
+
+	flag.Delimiter = delimiter
+	flag.ClpFlag.DefaultValue = formatValues(flag.DefaultValue, delimiter)
+
+	return c
+}
+
+// SetFlagEnv binds a flag to environment variables. If the flag is not provided by the user,
+// the value of the first non-empty environment variable is used before the default-value of the flag
+// (the value of a `Bool` flag must be a boolean like `true` or `1`). The name argument is the long-name of the flag.
+// A required flag is satisfied by an environment variable. The environment variables are displayed in the usage of the command.
+func (c *Command) SetFlagEnv(name string, envVars ...string) *Command {
+	flag, ok := c.Flags[strings.TrimPrefix(removeWhitespaces(name), "no-")]
+	if !ok {
+		c.commandRegistry.fail("--%s flag is not registered", name)
+	}
+
+	flag.EnvVars = append(flag.EnvVars, envVars...)
+
+	return c
+}
+
+// SetFlagChoices sets the valid values of a flag, for example `SetFlagChoices("type", "simple", "class", "hook")`.
+// The name argument is the long-name of the flag. Every value of a repeatable flag must be one of the choices.
+// The choices are displayed in the usage of the command and completed by the shell completion script.
+// The choices can not be set for a `Bool` or a `StringMap` flag, and the default-value of the flag must be one of the choices.
+func (c *Command) SetFlagChoices(name string, choices ...string) *Command {
+	flag, ok := c.Flags[removeWhitespaces(name)]
+	if !ok || flag.DataType == Bool || flag.DataType == StringMap {
+		c.commandRegistry.fail("--%s flag is not a registered flag with a value", name)
+	}
+
+	// default-value must be a valid value
+	defaultValues := []string{flag.ClpFlag.DefaultValue}
+	if isRepeatableDataType(flag.DataType) {
+		defaultValues = splitValues(defaultValues, defaultFlagDelimiter)
+	}
+
+	if !flag.IsRequired && !isChoice(choices, defaultValues...) {
+		c.commandRegistry.fail("default-value of the --%s flag must be one of %s", name, strings.Join(choices, ", "))
+	}
+
+	flag.Choices = choices
+
+	return c
+}
+
+// SetArgumentChoices sets the valid values of an argument, for example `SetArgumentChoices("shell", "bash", "zsh")`.
+// Every value of a variadic argument must be one of the choices. The choices are displayed in the usage
+// of the command and completed by the shell completion script. The default-value of the argument must be one of the choices.
+func (c *Command) SetArgumentChoices(name string, choices ...string) *Command {
+	arg, ok := c.Args[strings.TrimSuffix(removeWhitespaces(name), "...")]
+	if !ok {
+		c.commandRegistry.fail("%s argument is not registered", name)
+	}
+
+	// default-value must be a valid value
+	if arg.ClpArg.DefaultValue != "" && !isChoice(choices, arg.ClpArg.DefaultValue) {
+		c.commandRegistry.fail("default-value of the %s argument must be one of %s", name, strings.Join(choices, ", "))
+	}
+
+	arg.Choices = choices
+
+	return c
+}
+
+// SetFlagValidators adds validators of the value of a flag, for example `SetFlagValidators("port", commando.Range(1, 65535))`.
+// The name argument is the long-name of the flag. The validators are called with the value converted to the data type
+// of the flag (a slice or a map for a repeatable flag) and they are displayed in the usage of the command.
+// The default-value of a flag is not validated, except the values of a repeatable flag which are always validated
+// (e.g. `NonEmpty` rejects a repeatable flag without values).
+func (c *Command) SetFlagValidators(name string, validators ...Validator) *Command {
+	flag, ok := c.Flags[strings.TrimPrefix(removeWhitespaces(name), "no-")]
+	if !ok {
+		c.commandRegistry.fail("--%s flag is not registered", name)
+	}
+
+	flag.Validators = append(flag.Validators, validators...)
+
+	return c
+}
+
+// SetArgumentValidators adds validators of the value of an argument, for example `SetArgumentValidators("file", commando.ExistingFile())`.
+// The validators are called with the value converted to the data type of the argument (a slice for a variadic argument)
+// and they are displayed in the usage of the command. The default-value of a non-variadic argument is not validated,
+// but the values of a variadic argument are always validated (e.g. `NonEmpty` rejects a variadic argument without values).
+func (c *Command) SetArgumentValidators(name string, validators ...Validator) *Command {
+	arg, ok := c.Args[strings.TrimSuffix(removeWhitespaces(name), "...")]
+	if !ok {
+		c.commandRegistry.fail("%s argument is not registered", name)
+	}
+
+	arg.Validators = append(arg.Validators, validators...)
+
 	return c
 }
 
+// collect the values of the repeatable flags (`StringSlice`, `IntSlice` and `StringMap`) of the command
+// and remove them from the command-line argument values. A repeatable flag without a value (or with an empty value)
+// is an usage error.
+func (c *Command) collectRepeatableFlags(values []string) ([]string, map[string][]string, error) {
+	remainingValues := make([]string, 0, len(values))
+	repeatedValues := make(map[string][]string)
+
+	for index := 0; index < len(values); index++ {
+		value := values[index]
+
+		// flag with or without a value (`--name=value`)
+		name, flagValue, hasValue := value, "", false
+		if position := strings.Index(value, "="); position != -1 {
+			name, flagValue, hasValue = value[:position], value[position+1:], true
+		}
+
+		var flag *Flag
+		if isFlag(value) {
+			flag = c.findFlag(name)
+		}
+
+		if flag == nil || !isRepeatableDataType(flag.DataType) {
+			remainingValues = append(remainingValues, value)
+			continue
+		}
+
+		// value is the next command-line argument (if it is not a flag)
+		if !hasValue && index+1 < len(values) && !isFlag(values[index+1]) {
+			index++
+			flagValue = values[index]
+		}
+
+		if flagValue == "" {
+			return nil, nil, newParseError(ErrMissingFlag, flag.ClpFlag.Name, nil, "value of the --%s flag can not be empty", flag.ClpFlag.Name)
+		}
+
+		repeatedValues[flag.ClpFlag.Name] = append(repeatedValues[flag.ClpFlag.Name], flagValue)
+	}
+
+	return remainingValues, repeatedValues, nil
+}
+
 // SetAction registers a callback function with a command configuration that
 // will execute after command-line arguments are parsed.
 // If an action function is already registered with a command, it won't get registered again.
@@ -714,6 +2386,49 @@ func (c *Command) SetAction(action func(map[string]ArgValue, map[string]FlagValu
 	return c
 }
 
+// SetActionE registers a callback function with a command configuration that
+// will execute after command-line arguments are parsed. Unlike `SetAction`, the function receives
+// a context which is cancelled on SIGINT or SIGTERM signal and it can return an error.
+// The error is displayed by `Parse` and the process exits with the failure exit code (see `ExitError`).
+// If an action function is already registered with a command, it won't get registered again.
+func (c *Command) SetActionE(action ActionFunc) *Command {
+
+	// set action if not set before
+	if c.ActionE == nil {
+		c.ActionE = action
+	}
+
+	return c
+}
+
+// SetPreRun registers a hook function which is executed before the action function of the command
+// and its sub-commands, for example to initialise logging or load credentials. The hooks of the parent commands
+// are executed first. If a hook returns an error, the action function is not executed and the error is returned
+// like the error of an action function. If a pre-run hook is already registered with a command, it won't get registered again.
+func (c *Command) SetPreRun(hook ActionFunc) *Command {
+
+	// set hook if not set before
+	if c.PreRun == nil {
+		c.PreRun = hook
+	}
+
+	return c
+}
+
+// SetPostRun registers a hook function which is executed after the action function of the command and its sub-commands
+// returns without an error. The hooks of the parent commands are executed last. Use a middleware (see `CommandRegistry.Use`)
+// to execute a function even if the action function fails.
+// If a post-run hook is already registered with a command, it won't get registered again.
+func (c *Command) SetPostRun(hook ActionFunc) *Command {
+
+	// set hook if not set before
+	if c.PostRun == nil {
+		c.PostRun = hook
+	}
+
+	return c
+}
+
 /*---------------------*/
 
 // Arg defines the configuration of an argument.
@@ -727,14 +2442,226 @@ type Arg struct {
 
 	// is argument required to be provided by the user
 	IsRequired bool
+
+	// function to get the completion candidates of the argument value (see `SetArgumentCompletion`)
+	Complete CompletionFunc
+
+	// valid values of the argument (see `SetArgumentChoices`)
+	Choices []string
+
+	// data type of the argument value (`String` by default, see `AddTypedArgument`)
+	DataType int
+
+	// value of an argument with the `Custom` data type (see `AddArgumentValue`)
+	CustomValue Value
+
+	// number of values of a variadic argument (see `SetArgumentArity`)
+	Arity Arity
+
+	// validators of the argument value (see `SetArgumentValidators`)
+	Validators []Validator
+}
+
+// Arity defines the minimum and maximum number of values of a variadic argument.
+type Arity struct {
+
+	// minimum number of values
+	Min int
+
+	// maximum number of values (`-1` for any number of values)
+	Max int
+}
+
+// AtLeast returns an arity of a variadic argument with at least `n` values.
+func AtLeast(n int) Arity {
+	return Arity{Min: n, Max: -1}
+}
+
+// AtMost returns an arity of a variadic argument with at most `n` values.
+func AtMost(n int) Arity {
+	return Arity{Min: 0, Max: n}
+}
+
+// Exactly returns an arity of a variadic argument with exactly `n` values.
+func Exactly(n int) Arity {
+	return Arity{Min: n, Max: n}
+}
+
+// Between returns an arity of a variadic argument with `min` to `max` values.
+func Between(min int, max int) Arity {
+	return Arity{Min: min, Max: max}
+}
+
+// String returns the description of the arity (e.g. `at least 1 value`).
+func (arity Arity) String() string {
+
+	// plural form of the number of values
+	values := func(n int) string {
+		if n == 1 {
+			return "1 value"
+		}
+
+		return fmt.Sprintf("%d values", n)
+	}
+
+	switch {
+	case arity.Max < 0:
+		return "at least " + values(arity.Min)
+	case arity.Min == arity.Max:
+		return "exactly " + values(arity.Min)
+	case arity.Min == 0:
+		return "at most " + values(arity.Max)
+	}
+
+	return fmt.Sprintf("%d to %s", arity.Min, values(arity.Max))
+}
+
+// convert the values of the argument to its data type (a slice for a variadic argument)
+func (arg *Arg) convert(values []string) (interface{}, error) {
+
+	// set the value of a custom argument
+	if arg.DataType == Custom {
+		if len(values) == 0 {
+			return arg.CustomValue, nil
+		}
+
+		return arg.CustomValue, arg.CustomValue.Set(values[0])
+	}
+
+	// value of a non-variadic argument (an empty string if the value is missing)
+	if !arg.ClpArg.IsVariadic {
+		if len(values) == 0 {
+			return reflect.Zero(dataTypeGoTypes[arg.DataType]).Interface(), nil
+		}
+
+		return convertValue(arg.DataType, values[0])
+	}
+
+	// values of a variadic argument
+	slice := reflect.MakeSlice(reflect.SliceOf(dataTypeGoTypes[arg.DataType]), 0, len(values))
+	for _, value := range values {
+		_value, err := convertValue(arg.DataType, value)
+		if err != nil {
+			return nil, err
+		}
+
+		slice = reflect.Append(slice, reflect.ValueOf(_value))
+	}
+
+	return slice.Interface(), nil
+}
+
+// ValueSource is the source of the value of a flag or an argument.
+type ValueSource int
+
+// sources of the values of the flags and the arguments
+const (
+	// SourceDefault indicates that the value is the default-value (the value is not provided).
+	SourceDefault ValueSource = iota
+
+	// SourceCommandLine indicates that the value is provided with the command-line arguments.
+	SourceCommandLine
+
+	// SourceEnv indicates that the value is provided with an environment variable.
+	SourceEnv
+
+	// SourceConfig indicates that the value is provided with the configuration file.
+	SourceConfig
+
+	// SourcePrompt indicates that the value is entered at an interactive prompt.
+	// It is reserved for interactive prompts, `Parse` does not prompt for the values.
+	SourcePrompt
+)
+
+// names of the value sources
+var valueSourceNames = map[ValueSource]string{
+	SourceDefault:     "default",
+	SourceCommandLine: "command-line",
+	SourceEnv:         "env",
+	SourceConfig:      "config",
+	SourcePrompt:      "prompt",
+}
+
+// String returns the name of the source (e.g. `command-line`).
+func (source ValueSource) String() string {
+	return valueSourceNames[source]
 }
 
 // ArgValue represents an argument value to pass as an argument in action function.
 type ArgValue struct {
 	Arg
 
-	// value of the argument
+	// value of the argument (values of a variadic argument are joined with a comma)
 	Value string
+
+	// values of the argument (a variadic argument can have multiple values)
+	Values []string
+
+	// value converted to the Go type of the data type of the argument (a slice for a variadic argument)
+	TypedValue interface{}
+
+	// source of the value
+	source ValueSource
+}
+
+// Source returns the source of the value of the argument (`SourceCommandLine` or `SourceDefault`).
+func (av ArgValue) Source() ValueSource {
+	return av.source
+}
+
+// IsSet returns `true` if the value of the argument is provided by the user (it is not the default-value).
+func (av ArgValue) IsSet() bool {
+	return av.source != SourceDefault
+}
+
+// GetStrings returns the values of an argument (a variadic argument can have multiple values).
+func (av ArgValue) GetStrings() []string {
+	return av.Values
+}
+
+// GetInt returns `int` value of a non-variadic argument.
+func (av ArgValue) GetInt() (int, error) {
+	if value, ok := av.TypedValue.(int); ok {
+		return value, nil
+	}
+
+	return 0, fmt.Errorf("%s argument can not be converted to int", av.ClpArg.Name)
+}
+
+// GetFloat returns `float64` value of a non-variadic argument.
+func (av ArgValue) GetFloat() (float64, error) {
+	if value, ok := av.TypedValue.(float64); ok {
+		return value, nil
+	}
+
+	return 0, fmt.Errorf("%s argument can not be converted to float64", av.ClpArg.Name)
+}
+
+// GetUint returns `uint` value of a non-variadic argument.
+func (av ArgValue) GetUint() (uint, error) {
+	if value, ok := av.TypedValue.(uint); ok {
+		return value, nil
+	}
+
+	return 0, fmt.Errorf("%s argument can not be converted to uint", av.ClpArg.Name)
+}
+
+// GetInt64 returns `int64` value of a non-variadic argument.
+func (av ArgValue) GetInt64() (int64, error) {
+	if value, ok := av.TypedValue.(int64); ok {
+		return value, nil
+	}
+
+	return 0, fmt.Errorf("%s argument can not be converted to int64", av.ClpArg.Name)
+}
+
+// GetDuration returns `time.Duration` value of a non-variadic argument.
+func (av ArgValue) GetDuration() (time.Duration, error) {
+	if value, ok := av.TypedValue.(time.Duration); ok {
+		return value, nil
+	}
+
+	return 0, fmt.Errorf("%s argument can not be converted to time.Duration", av.ClpArg.Name)
 }
 
 /*---------------------*/
@@ -742,6 +2669,9 @@ type ArgValue struct {
 // Flag defines the configuration of a flag.
 type Flag struct {
 
+	// registration order of the flag
+	order int
+
 	// clapper flag config
 	ClpFlag *clapper.Flag
 
@@ -756,6 +2686,77 @@ type Flag struct {
 
 	// is flag required to be provided by the user
 	IsRequired bool
+
+	// function to get the completion candidates of the flag value (see `SetFlagCompletion`)
+	Complete CompletionFunc
+
+	// delimiter to split each value of a repeatable flag (see `SetFlagDelimiter`)
+	Delimiter string
+
+	// valid values of the flag (see `SetFlagChoices`)
+	Choices []string
+
+	// value of a flag with the `Custom` data type (see `AddFlagValue`)
+	CustomValue Value
+
+	// validators of the flag value (see `SetFlagValidators`)
+	Validators []Validator
+
+	// environment variables to get the value from if the flag is not provided (see `SetFlagEnv`)
+	EnvVars []string
+
+	// command which registered the flag
+	command *Command
+}
+
+// EnvNames returns the names of the environment variables bound to the flag in the order of precedence
+// (environment variables set with `SetFlagEnv` and the environment variable with the prefix of the registry).
+func (flag *Flag) EnvNames() []string {
+	names := append([]string{}, flag.EnvVars...)
+
+	// built-in flags are not bound automatically
+	if flag.command == nil || flag.ClpFlag.Name == helpFlagName || flag.ClpFlag.Name == versionFlagName {
+		return names
+	}
+
+	if prefix := flag.command.commandRegistry.EnvPrefix; prefix != "" {
+		parts := append([]string{prefix}, strings.Fields(flag.command.Path())...)
+		parts = append(parts, flag.ClpFlag.Name)
+
+		name := strings.ToUpper(strings.Join(parts, "_"))
+		names = append(names, strings.NewReplacer("-", "_", ".", "_").Replace(name))
+	}
+
+	return names
+}
+
+// get the name and value of the first non-empty environment variable bound to the flag
+func (flag *Flag) lookupEnv() (string, string, bool) {
+	for _, name := range flag.EnvNames() {
+		if value := os.Getenv(name); value != "" {
+			return name, value, true
+		}
+	}
+
+	return "", "", false
+}
+
+// Value is the interface of a custom flag value (see `AddFlagValue`).
+type Value interface {
+
+	// Set parses a command-line argument value and sets the value
+	Set(value string) error
+
+	// String returns the value as a string
+	String() string
+
+	// Type returns the name of the data type for the error messages (e.g. `url`)
+	Type() string
+}
+
+// IsRepeatable returns `true` if the flag accumulates the values of all its occurrences.
+func (flag *Flag) IsRepeatable() bool {
+	return isRepeatableDataType(flag.DataType)
 }
 
 // FlagValue represents a flag value to pass as an argument in action function.
@@ -763,6 +2764,19 @@ type Flag struct {
 type FlagValue struct {
 	Flag
 	Value interface{}
+
+	// source of the value
+	source ValueSource
+}
+
+// Source returns the source of the value of the flag (command-line, environment variable, config file or default-value).
+func (fv FlagValue) Source() ValueSource {
+	return fv.source
+}
+
+// IsSet returns `true` if the value of the flag is provided by the user (it is not the default-value).
+func (fv FlagValue) IsSet() bool {
+	return fv.source != SourceDefault
 }
 
 // GetBool returns `bool` value of a flag.
@@ -792,6 +2806,69 @@ func (fv FlagValue) GetString() (string, error) {
 	return "", fmt.Errorf("%s flag can not be converted to string", fv.ClpFlag.Name)
 }
 
+// GetStringSlice returns `[]string` value of a repeatable flag.
+func (fv FlagValue) GetStringSlice() ([]string, error) {
+	if fv.DataType == StringSlice {
+		return fv.Value.([]string), nil
+	}
+
+	return nil, fmt.Errorf("%s flag can not be converted to []string", fv.ClpFlag.Name)
+}
+
+// GetIntSlice returns `[]int` value of a repeatable flag.
+func (fv FlagValue) GetIntSlice() ([]int, error) {
+	if fv.DataType == IntSlice {
+		return fv.Value.([]int), nil
+	}
+
+	return nil, fmt.Errorf("%s flag can not be converted to []int", fv.ClpFlag.Name)
+}
+
+// GetStringMap returns `map[string]string` value of a repeatable flag.
+func (fv FlagValue) GetStringMap() (map[string]string, error) {
+	if fv.DataType == StringMap {
+		return fv.Value.(map[string]string), nil
+	}
+
+	return nil, fmt.Errorf("%s flag can not be converted to map[string]string", fv.ClpFlag.Name)
+}
+
+// GetFloat returns `float64` value of a flag.
+func (fv FlagValue) GetFloat() (float64, error) {
+	if fv.DataType == Float {
+		return fv.Value.(float64), nil
+	}
+
+	return 0, fmt.Errorf("%s flag can not be converted to float64", fv.ClpFlag.Name)
+}
+
+// GetUint returns `uint` value of a flag.
+func (fv FlagValue) GetUint() (uint, error) {
+	if fv.DataType == Uint {
+		return fv.Value.(uint), nil
+	}
+
+	return 0, fmt.Errorf("%s flag can not be converted to uint", fv.ClpFlag.Name)
+}
+
+// GetInt64 returns `int64` value of a flag.
+func (fv FlagValue) GetInt64() (int64, error) {
+	if fv.DataType == Int64 {
+		return fv.Value.(int64), nil
+	}
+
+	return 0, fmt.Errorf("%s flag can not be converted to int64", fv.ClpFlag.Name)
+}
+
+// GetDuration returns `time.Duration` value of a flag.
+func (fv FlagValue) GetDuration() (time.Duration, error) {
+	if fv.DataType == Duration {
+		return fv.Value.(time.Duration), nil
+	}
+
+	return 0, fmt.Errorf("%s flag can not be converted to time.Duration", fv.ClpFlag.Name)
+}
+
 /*---------------------*/
 
 // SetExecutableName sets the executable name of the `DefaultCommandRegistry` registry.
@@ -808,3 +2885,8 @@ func Register(name interface{}) *Command {
 func Parse(osArgs []string) {
 	DefaultCommandRegistry.Parse(osArgs)
 }
+
+// ParseE parses the command-line arguments for the `DefaultCommandRegistry` registry and returns an error.
+func ParseE(osArgs []string) error {
+	return DefaultCommandRegistry.ParseE(osArgs)
+}
@@ -1,71 +1,113 @@
 package commando
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/thatisuday/clapper"
 )
 
 /*----------------*/
 
+// compiled test programs (source file => executable path)
+var testPrograms = make(map[string]string)
+
+// remove compiled test programs after running the tests
+func TestMain(m *testing.M) {
+	code := m.Run()
+
+	for _, executable := range testPrograms {
+		os.RemoveAll(filepath.Dir(executable))
+	}
+
+	os.Exit(code)
+}
+
+// build a test program (once) and run it with the command-line arguments.
+// It returns the output (standard output followed by standard error) and the exit code of the process.
+func runTestProgram(t *testing.T, env []string, file string, args ...string) (string, int) {
+	stdout, stderr, code := runTestProgramStreams(t, env, file, args...)
+
+	return stdout + stderr, code
+}
+
+// build a test program (once) and run it with the command-line arguments.
+// It returns the standard output, the standard error and the exit code of the process.
+func runTestProgramStreams(t *testing.T, env []string, file string, args ...string) (string, string, int) {
+
+	// build the test program (`go run` does not preserve the exit code)
+	if _, ok := testPrograms[file]; !ok {
+		dir, err := ioutil.TempDir("", "commando")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		executable := filepath.Join(dir, strings.TrimSuffix(filepath.Base(file), ".go"))
+		if output, err := exec.Command("go", "build", "-o", executable, file).CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, output)
+		}
+
+		testPrograms[file] = executable
+	}
+
+	// command
+	var stdout, stderr strings.Builder
+	cmd := exec.Command(testPrograms[file], args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	// get output and exit code
+	err := cmd.Run()
+	if exitError, ok := err.(*exec.ExitError); ok {
+		return stdout.String(), stderr.String(), exitError.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	return stdout.String(), stderr.String(), 0
+}
+
 // executable name should not be empty
 func TestEmptyExecutableName(t *testing.T) {
-	// command
-	cmd := exec.Command("go", "run", "tests/empty-exec-name.go")
+	output, code := runTestProgram(t, nil, "tests/empty-exec-name.go")
 
-	// get output
-	if output, err := cmd.Output(); err != nil {
-		fmt.Println("Error:", err)
-	} else {
-		if !strings.Contains(fmt.Sprintf("%s", output), "Error: executable name must be a non-empty string.") {
-			t.Fail()
-		}
+	if code != ExitCodeFailure || !strings.Contains(output, "Error: executable name must be a non-empty string.") {
+		t.Fail()
 	}
 }
 
 // a sub-command must have an action function
 func TestMissingActionFunction(t *testing.T) {
-	// command
-	cmdRoot := exec.Command("go", "run", "tests/missing-action-function.go")
+	output, code := runTestProgram(t, nil, "tests/missing-action-function.go")
 
-	// get output
-	if output, err := cmdRoot.Output(); err != nil {
-		fmt.Println("Error:", err)
-	} else {
-		if fmt.Sprintf("%s", output) != "" {
-			t.Fail()
-		}
+	if code != ExitCodeSuccess || output != "" {
+		t.Fail()
 	}
 
 	/*---------------*/
 
-	// command
-	cmdCreate := exec.Command("go", "run", "tests/missing-action-function.go", "create")
+	output, code = runTestProgram(t, nil, "tests/missing-action-function.go", "create")
 
-	// get output
-	if output, err := cmdCreate.Output(); err != nil {
-		fmt.Println("Error:", err)
-	} else {
-		if !strings.Contains(fmt.Sprintf("%s", output), "Error: action function for the create command is not registered.") {
-			t.Fail()
-		}
+	if code != ExitCodeFailure || !strings.Contains(output, "Error: action function for the create command is not registered.") {
+		t.Fail()
 	}
 }
 
 // default value of a flag must match the data type
 func TestInvalidDefaultValue(t *testing.T) {
-	// command
-	cmdCreate := exec.Command("go", "run", "tests/invalid-default-value.go", "create")
+	output, code := runTestProgram(t, nil, "tests/invalid-default-value.go", "create")
 
-	// get output
-	if output, err := cmdCreate.Output(); err != nil {
-		fmt.Println("Error:", err)
-	} else {
-		if !strings.Contains(fmt.Sprintf("%s", output), "Error: value of the --dir flag must be a string or nil.") {
-			t.Fail()
-		}
+	if code != ExitCodeFailure || !strings.Contains(output, "Error: value of the --dir flag must be a string or nil.") {
+		t.Fail()
 	}
 }
 
@@ -73,17 +115,18 @@ func TestInvalidDefaultValue(t *testing.T) {
 
 // unknown command show display an error
 func TestUnknownCommand(t *testing.T) {
-	// command
-	cmdPrint := exec.Command("go", "run", "tests/valid-registry.go", "print")
-	cmdPrint.Env = append(os.Environ(), "NO_ROOT=TRUE")
-
-	// get output
-	if output, err := cmdPrint.Output(); err != nil {
-		fmt.Println("Error:", err)
-	} else {
-		if !strings.Contains(fmt.Sprintf("%s", output), "Error: print is not a valid command.") {
-			t.Fail()
-		}
+	output, code := runTestProgram(t, []string{"NO_ROOT=TRUE"}, "tests/valid-registry.go", "print")
+
+	if code != ExitCodeUsage || !strings.Contains(output, "Error: print is not a valid command.") {
+		t.Fail()
+	}
+
+	/*----------------*/
+
+	output, _ = runTestProgram(t, []string{"NO_ROOT=TRUE"}, "tests/valid-registry.go", "craete")
+
+	if !strings.Contains(output, "Error: craete is not a valid command.\n\nDid you mean this?\n   create\n") {
+		t.Fail()
 	}
 }
 
@@ -93,143 +136,129 @@ func TestUnsupportedFlag(t *testing.T) {
 	unsupportedFlags := []string{"---version", "-version"}
 
 	for _, flag := range unsupportedFlags {
-		// command
-		cmdRoot := exec.Command("go", "run", "tests/valid-registry.go", flag)
-
-		// get output
-		if output, err := cmdRoot.Output(); err != nil {
-			fmt.Println("Error:", err)
-		} else {
-			if !strings.Contains(fmt.Sprintf("%s", output), fmt.Sprintf("Error: %s is not a supported flag.", flag)) {
-				t.Fail()
-			}
+		output, code := runTestProgram(t, nil, "tests/valid-registry.go", flag)
+
+		if code != ExitCodeUsage || !strings.Contains(output, fmt.Sprintf("Error: %s is not a supported flag.", flag)) {
+			t.Fail()
 		}
 	}
 }
 
 // missing argument value of a required argument must display an error
 func TestMissingArgument(t *testing.T) {
-	// command
-	cmdRoot := exec.Command("go", "run", "tests/valid-registry.go")
+	output, code := runTestProgram(t, nil, "tests/valid-registry.go")
 
-	// get output
-	if output, err := cmdRoot.Output(); err != nil {
-		fmt.Println("Error:", err)
-	} else {
-		if !strings.Contains(fmt.Sprintf("%s", output), "Error: value of the category argument can not be empty.") {
-			t.Fail()
-		}
+	if code != ExitCodeUsage || !strings.Contains(output, "Error: value of the category argument can not be empty.") {
+		t.Fail()
 	}
 
 	/*----------------*/
 
-	// command
-	cmdCreate := exec.Command("go", "run", "tests/valid-registry.go", "create")
+	output, code = runTestProgram(t, nil, "tests/valid-registry.go", "create")
 
-	// get output
-	if output, err := cmdCreate.Output(); err != nil {
-		fmt.Println("Error:", err)
-	} else {
-		if !strings.Contains(fmt.Sprintf("%s", output), "Error: value of the name argument can not be empty.") {
-			t.Fail()
-		}
+	if code != ExitCodeUsage || !strings.Contains(output, "Error: value of the name argument can not be empty.") {
+		t.Fail()
 	}
 }
 
 // missing flag value of a required flag must display an error
 func TestMissingFlag(t *testing.T) {
-	// command
-	cmdCreate := exec.Command("go", "run", "tests/valid-registry.go", "create", "my-service")
+	output, code := runTestProgram(t, nil, "tests/valid-registry.go", "create", "my-service")
 
-	// get output
-	if output, err := cmdCreate.Output(); err != nil {
-		fmt.Println("Error:", err)
-	} else {
-		if !strings.Contains(fmt.Sprintf("%s", output), "Error: value of the --dir flag can not be empty.") {
-			t.Fail()
-		}
+	if code != ExitCodeUsage || !strings.Contains(output, "Error: value of the --dir flag can not be empty.") {
+		t.Fail()
 	}
 }
 
 // wrong value of a flag must display an error
 func TestInvalidFlagValue(t *testing.T) {
-	// command
-	cmdCreate := exec.Command("go", "run", "tests/valid-registry.go", "create", "my-service", "-d", "./services/my-service", "--timeout", "10sec")
+	output, code := runTestProgram(t, nil, "tests/valid-registry.go", "create", "my-service", "-d", "./services/my-service", "--timeout", "10sec")
 
-	// get output
-	if output, err := cmdCreate.Output(); err != nil {
-		fmt.Println("Error:", err)
-	} else {
-		if !strings.Contains(fmt.Sprintf("%s", output), "Error: value of the --timeout flag must be an integer.") {
-			t.Fail()
+	if code != ExitCodeUsage || !strings.Contains(output, "Error: value of the --timeout flag must be an integer.") {
+		t.Fail()
+	}
+}
+
+// an action function can choose the exit code by returning an `*ExitError` value
+func TestExitCodes(t *testing.T) {
+	registry := NewCommandRegistry().SetExitCodes(64, 70)
+
+	testCases := []struct {
+		err  error
+		code int
+	}{
+		{newParseError(ErrMissingFlag, "dir", nil, "value of the --dir flag can not be empty"), 64},
+		{newParseError(ErrMissingAction, "create", nil, "action function for the create command is not registered"), 70},
+		{errors.New("connection refused"), 70},
+		{&ExitError{Code: 3}, 3},
+		{fmt.Errorf("deploy: %w", &ExitError{Code: 4, Err: errors.New("timeout")}), 4},
+	}
+
+	for _, testCase := range testCases {
+		if code := registry.exitCode(testCase.err); code != testCase.code {
+			t.Errorf("%v: expected exit code %d, got %d", testCase.err, testCase.code, code)
 		}
 	}
 }
 
 // test if all values of a root-command are valid
 func TestValidRootCommand(t *testing.T) {
-	// command
-	cmdCreate := exec.Command("go", "run", "tests/valid-registry.go", "service")
+	output, code := runTestProgram(t, nil, "tests/valid-registry.go", "service")
 
-	// get output
-	if output, err := cmdCreate.Output(); err != nil {
-		fmt.Println("Error:", err)
-	} else {
-		values := []string{
-			"arg -> category: service(string)",
-			"flag -> verbose: false(bool)",
-			"flag -> version: false(bool)",
-			"flag -> help: false(bool)",
-		}
+	if code != ExitCodeSuccess {
+		t.Fatalf("unexpected exit code %d: %s", code, output)
+	}
 
-		for _, value := range values {
-			if !strings.Contains(fmt.Sprintf("%s", output), value) {
-				t.Fail()
-			}
+	values := []string{
+		"arg -> category: service(string)",
+		"flag -> verbose: false(bool)",
+		"flag -> version: false(bool)",
+		"flag -> help: false(bool)",
+	}
+
+	for _, value := range values {
+		if !strings.Contains(output, value) {
+			t.Fail()
 		}
 	}
 }
 
 // test if default value of an argument is correct
 func TestDefaultArgValue(t *testing.T) {
-	// command
-	cmdCreate := exec.Command("go", "run", "tests/valid-registry.go", "create", "my-service", "-t", "service", "--dir=./service/my-service", "--timeout", "10", "-v")
+	output, code := runTestProgram(t, nil, "tests/valid-registry.go", "create", "my-service", "-t", "service", "--dir=./service/my-service", "--timeout", "10", "-v")
 
-	// get output
-	if output, err := cmdCreate.Output(); err != nil {
-		fmt.Println("Error:", err)
-	} else {
-		if !strings.Contains(fmt.Sprintf("%s", output), "arg -> version: 1.0.0(string)") {
-			t.Fail()
-		}
+	if code != ExitCodeSuccess {
+		t.Fatalf("unexpected exit code %d: %s", code, output)
+	}
+
+	if !strings.Contains(output, "arg -> version: 1.0.0(string)") {
+		t.Fail()
 	}
 }
 
 // test if all values of a sub-command are valid
 func TestValidSubCommand(t *testing.T) {
-	// command
-	cmdCreate := exec.Command("go", "run", "tests/valid-registry.go", "create", "my-service", "1.0.0", "file1.txt", "file2.txt", "-t", "service", "--dir=./service/my-service", "--timeout", "10", "-v", "--no-clean")
+	output, code := runTestProgram(t, nil, "tests/valid-registry.go", "create", "my-service", "1.0.0", "file1.txt", "file2.txt", "-t", "service", "--dir=./service/my-service", "--timeout", "10", "-v", "--no-clean")
 
-	// get output
-	if output, err := cmdCreate.Output(); err != nil {
-		fmt.Println("Error:", err)
-	} else {
-		values := []string{
-			"arg -> version: 1.0.0(string)",
-			"arg -> name: my-service(string)",
-			"arg -> files: file1.txt,file2.txt(string)",
-			"flag -> dir: ./service/my-service(string)",
-			"flag -> type: service(string)",
-			"flag -> timeout: 10(int)",
-			"flag -> verbose: true(bool)",
-			"flag -> help: false(bool)",
-			"flag -> clean: false(bool)",
-		}
+	if code != ExitCodeSuccess {
+		t.Fatalf("unexpected exit code %d: %s", code, output)
+	}
 
-		for _, value := range values {
-			if !strings.Contains(fmt.Sprintf("%s", output), value) {
-				t.Fail()
-			}
+	values := []string{
+		"arg -> version: 1.0.0(string)",
+		"arg -> name: my-service(string)",
+		"arg -> files: file1.txt,file2.txt(string)",
+		"flag -> dir: ./service/my-service(string)",
+		"flag -> type: service(string)",
+		"flag -> timeout: 10(int)",
+		"flag -> verbose: true(bool)",
+		"flag -> help: false(bool)",
+		"flag -> clean: false(bool)",
+	}
+
+	for _, value := range values {
+		if !strings.Contains(output, value) {
+			t.Fail()
 		}
 	}
 }
@@ -240,16 +269,14 @@ func TestValidVersion(t *testing.T) {
 	versionTriggers := []string{"-v", "--version", "version"}
 
 	for _, versionTrigger := range versionTriggers {
-		// command
-		cmdCreate := exec.Command("go", "run", "tests/valid-registry.go", versionTrigger)
-
-		// get output
-		if output, err := cmdCreate.Output(); err != nil {
-			fmt.Println("Error:", err)
-		} else {
-			if !strings.Contains(fmt.Sprintf("%s", output), "Version: v1.0.0") {
-				t.Fail()
-			}
+		output, code := runTestProgram(t, nil, "tests/valid-registry.go", versionTrigger)
+
+		if code != ExitCodeSuccess {
+			t.Fatalf("unexpected exit code %d: %s", code, output)
+		}
+
+		if !strings.Contains(output, "Version: v1.0.0") {
+			t.Fail()
 		}
 	}
 }
@@ -260,41 +287,39 @@ func TestValidRootCommandUsage(t *testing.T) {
 	helpTriggers := []string{"-h", "--help", "help"}
 
 	for _, helpTrigger := range helpTriggers {
-		// command
-		cmdCreate := exec.Command("go", "run", "tests/valid-registry.go", helpTrigger)
-
-		// get output
-		if output, err := cmdCreate.Output(); err != nil {
-			fmt.Println("Error:", err)
-		} else {
-			values := []string{
-				"Reactor is a command-line tool to generate React projects.",
-				"It helps you create components, write test cases, start a development server and much more.",
-
-				"Usage:",
-				"reactor <category> {flags}",
-				"reactor <command> {flags}",
-
-				"Commands: ",
-				"build                         creates build artifacts",
-				"create                        creates a component",
-				"help                          displays usage information",
-				"serve                         starts a development server",
-				"version                       displays version number",
-
-				"Arguments: ",
-				"category                      category of the information to look for",
-
-				"Flags: ",
-				"-h, --help                    displays usage information of the application or a command (default: false)",
-				"-V, --verbose                 display log information (default: false)",
-				"-v, --version                 displays version number (default: false)",
-			}
+		output, code := runTestProgram(t, nil, "tests/valid-registry.go", helpTrigger)
+
+		if code != ExitCodeSuccess {
+			t.Fatalf("unexpected exit code %d: %s", code, output)
+		}
+
+		values := []string{
+			"Reactor is a command-line tool to generate React projects.",
+			"It helps you create components, write test cases, start a development server and much more.",
+
+			"Usage:",
+			"reactor <category> {flags}",
+			"reactor <command> {flags}",
+
+			"Commands: ",
+			"build                         creates build artifacts",
+			"create                        creates a component",
+			"help                          displays usage information",
+			"serve                         starts a development server",
+			"version                       displays version number",
+
+			"Arguments: ",
+			"category                      category of the information to look for",
 
-			for _, value := range values {
-				if !strings.Contains(fmt.Sprintf("%s", output), value) {
-					t.Fail()
-				}
+			"Flags: ",
+			"-h, --help                    displays usage information of the application or a command (default: false)",
+			"-V, --verbose                 display log information (default: false)",
+			"-v, --version                 displays version number (default: false)",
+		}
+
+		for _, value := range values {
+			if !strings.Contains(output, value) {
+				t.Fail()
 			}
 		}
 	}
@@ -306,37 +331,35 @@ func TestValidSubCommandUsage(t *testing.T) {
 	helpTriggers := []string{"-h", "--help"}
 
 	for _, helpTrigger := range helpTriggers {
-		// command
-		cmdCreate := exec.Command("go", "run", "tests/valid-registry.go", "create", helpTrigger)
-
-		// get output
-		if output, err := cmdCreate.Output(); err != nil {
-			fmt.Println("Error:", err)
-		} else {
-			values := []string{
-				"This command creates a component of a given type and outputs component files in the project directory.",
-
-				"Usage:",
-				"reactor <name> [version] [files] {flags}",
-
-				"Arguments: ",
-				"name                          name of the component to create",
-				"version                       version of the component (default: 1.0.0)",
-				"files                         files to remove once component is created {variadic}",
-
-				"Flags: ",
-				"-d, --dir                     output directory for the component files",
-				"-h, --help                    displays usage information of the application or a command",
-				"--timeout                     operation timeout in seconds (default: 60)",
-				"-t, --type                    type of the component to create (default: simple_type)",
-				"-v, --verbose                 display logs while creating the component files (default: false)",
-				"--no-clean                    avoid cleanup of the component directory (default: false)",
-			}
+		output, code := runTestProgram(t, nil, "tests/valid-registry.go", "create", helpTrigger)
+
+		if code != ExitCodeSuccess {
+			t.Fatalf("unexpected exit code %d: %s", code, output)
+		}
+
+		values := []string{
+			"This command creates a component of a given type and outputs component files in the project directory.",
+
+			"Usage:",
+			"reactor create <name> [version] [files]... {flags}",
+
+			"Arguments: ",
+			"name                          name of the component to create",
+			"version                       version of the component (default: 1.0.0)",
+			"files                         files to remove once component is created {variadic}",
+
+			"Flags: ",
+			"-d, --dir                     output directory for the component files",
+			"-h, --help                    displays usage information of the application or a command",
+			"--timeout                     operation timeout in seconds (default: 60)",
+			"-t, --type                    type of the component to create (default: simple_type)",
+			"-v, --verbose                 display logs while creating the component files (default: false)",
+			"--no-clean                    avoid cleanup of the component directory (default: false)",
+		}
 
-			for _, value := range values {
-				if !strings.Contains(fmt.Sprintf("%s", output), value) {
-					t.Fail()
-				}
+		for _, value := range values {
+			if !strings.Contains(output, value) {
+				t.Fail()
 			}
 		}
 	}
@@ -346,30 +369,1580 @@ func TestValidSubCommandUsage(t *testing.T) {
 func TestEvents(t *testing.T) {
 
 	// test `version` event
-	cmdRootVersion := exec.Command("go", "run", "tests/valid-registry.go", "--version")
-	cmdRootVersion.Env = append(os.Environ(), "LISTEN_EVENTS=TRUE")
-
-	// get output
-	if output, err := cmdRootVersion.Output(); err != nil {
-		fmt.Println("Error:", err)
-	} else {
-		if !strings.Contains(fmt.Sprintf("%s", output), fmt.Sprintf("event-name: %s", EventVersion)) {
-			t.Fail()
-		}
+	output, code := runTestProgram(t, []string{"LISTEN_EVENTS=TRUE"}, "tests/valid-registry.go", "--version")
+
+	if code != ExitCodeSuccess {
+		t.Fatalf("unexpected exit code %d: %s", code, output)
+	}
+
+	if !strings.Contains(output, fmt.Sprintf("event-name: %s", EventVersion)) {
+		t.Fail()
 	}
 
 	/*--------------*/
 
 	// test `help` event
-	cmdRootHelp := exec.Command("go", "run", "tests/valid-registry.go", "--help")
-	cmdRootHelp.Env = append(os.Environ(), "LISTEN_EVENTS=TRUE")
-
-	// get output
-	if output, err := cmdRootHelp.Output(); err != nil {
-		fmt.Println("Error:", err)
-	} else {
-		if !strings.Contains(fmt.Sprintf("%s", output), fmt.Sprintf("event-name: %s", EventHelp)) {
-			t.Fail()
+	output, code = runTestProgram(t, []string{"LISTEN_EVENTS=TRUE"}, "tests/valid-registry.go", "--help")
+
+	if code != ExitCodeSuccess {
+		t.Fatalf("unexpected exit code %d: %s", code, output)
+	}
+
+	if !strings.Contains(output, fmt.Sprintf("event-name: %s", EventHelp)) {
+		t.Fail()
+	}
+}
+
+/*----------------*/
+
+// create a registry with a `create` sub-command for in-process tests
+func newTestRegistry(action func(map[string]ArgValue, map[string]FlagValue)) *CommandRegistry {
+	registry := NewCommandRegistry().SetExecutableName("reactor")
+
+	registry.
+		Register("create").
+		AddArgument("name", "name of the component to create", "").
+		AddFlag("dir,d", "output directory for the component files", String, nil).
+		AddFlag("timeout", "operation timeout in seconds", Int, 60).
+		SetAction(action)
+
+	return registry
+}
+
+// ParseE must return typed errors instead of exiting the process
+func TestParseErrors(t *testing.T) {
+	registry := newTestRegistry(func(map[string]ArgValue, map[string]FlagValue) {})
+
+	testCases := []struct {
+		args []string
+		kind error
+		name string
+	}{
+		{[]string{"print"}, ErrUnknownCommand, "print"},
+		{[]string{"create", "--force"}, ErrUnknownFlag, "--force"},
+		{[]string{"create", "---dir"}, ErrUnsupportedFlag, "---dir"},
+		{[]string{"create"}, ErrMissingArgument, "name"},
+		{[]string{"create", "my-service"}, ErrMissingFlag, "dir"},
+		{[]string{"create", "my-service", "-d", "./out", "--timeout", "10sec"}, ErrInvalidFlagValue, "timeout"},
+	}
+
+	for _, testCase := range testCases {
+		err := registry.ParseE(testCase.args)
+
+		if !errors.Is(err, testCase.kind) {
+			t.Errorf("%v: expected %v error, got %v", testCase.args, testCase.kind, err)
+			continue
+		}
+
+		var parseError *ParseError
+		if !errors.As(err, &parseError) || parseError.Name != testCase.name {
+			t.Errorf("%v: expected error for %s, got %v", testCase.args, testCase.name, err)
+		}
+	}
+
+	// `clapper` errors must be accessible
+	var errorUnknownCommand clapper.ErrorUnknownCommand
+	if err := registry.ParseE([]string{"print"}); !errors.As(err, &errorUnknownCommand) {
+		t.Errorf("expected clapper.ErrorUnknownCommand, got %v", err)
+	}
+}
+
+// ParseE must execute the action function with fresh values on every call
+func TestParseExecutesAction(t *testing.T) {
+	var dir string
+	var timeout int
+
+	registry := newTestRegistry(func(args map[string]ArgValue, flags map[string]FlagValue) {
+		dir, _ = flags["dir"].GetString()
+		timeout, _ = flags["timeout"].GetInt()
+	})
+
+	if err := registry.ParseE([]string{"create", "my-service", "-d", "./out", "--timeout", "10"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if dir != "./out" || timeout != 10 {
+		t.Errorf("unexpected flag values: %s, %d", dir, timeout)
+	}
+
+	// values of the previous call must not leak
+	if err := registry.ParseE([]string{"create", "my-service", "-d", "./build"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if dir != "./build" || timeout != 60 {
+		t.Errorf("unexpected flag values: %s, %d", dir, timeout)
+	}
+}
+
+// ParseE must return the error of an action function registered with `SetActionE`
+func TestActionError(t *testing.T) {
+	errDeploy := errors.New("deployment failed")
+
+	registry := NewCommandRegistry()
+	registry.
+		Register("deploy").
+		AddArgument("name", "name of the service", "").
+		SetActionE(func(ctx context.Context, args map[string]ArgValue, flags map[string]FlagValue) error {
+			if ctx == nil || ctx.Err() != nil {
+				t.Error("expected an active context")
+			}
+
+			if args["name"].Value == "broken" {
+				return errDeploy
+			}
+
+			return nil
+		})
+
+	if err := registry.ParseE([]string{"deploy", "web"}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	if err := registry.ParseE([]string{"deploy", "broken"}); err != errDeploy {
+		t.Errorf("expected %v, got %v", errDeploy, err)
+	}
+
+	if code := registry.exitCode(errDeploy); code != ExitCodeFailure {
+		t.Errorf("expected exit code %d, got %d", ExitCodeFailure, code)
+	}
+}
+
+// nested sub-commands must be parsed and looked up by their path
+func TestNestedCommands(t *testing.T) {
+	var added string
+
+	registry := NewCommandRegistry()
+	node := registry.Register("cluster").Register("node")
+	node.
+		Register("add").
+		AddArgument("name", "name of the node", "").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			added = args["name"].Value
+		})
+
+	if err := registry.ParseE([]string{"cluster", "node", "add", "node-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if added != "node-1" {
+		t.Errorf("expected node-1, got %s", added)
+	}
+
+	// unknown nested command
+	if err := registry.ParseE([]string{"cluster", "nod", "add"}); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("expected %v error, got %v", ErrUnknownCommand, err)
+	}
+
+	// registration must return the existing command
+	if registry.Register("cluster").Register("node") != node {
+		t.Error("expected the registered command")
+	}
+
+	// lookup by path
+	if command, err := registry.lookupCommand([]string{"cluster", "node", "add"}); err != nil || command.Path() != "cluster node add" {
+		t.Errorf("unexpected lookup result: %v", err)
+	}
+
+	if _, err := registry.lookupCommand([]string{"cluster", "pod"}); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("expected %v error, got %v", ErrUnknownCommand, err)
+	}
+}
+
+// flags before the name of a (nested) sub-command must be skipped while looking up the command
+func TestFlagsBeforeCommands(t *testing.T) {
+	var executed, result string
+
+	registry := NewCommandRegistry()
+	registry.
+		Register(nil).
+		AddPersistentFlag("verbose,V", "display log information", Bool, nil).
+		AddPersistentFlag("context", "cluster context", String, "default").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			executed = "root"
+		})
+
+	registry.
+		Register("alpha").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			verbose, _ := flags["verbose"].GetBool()
+			executed, result = "alpha", fmt.Sprint(verbose)
+		})
+
+	registry.
+		Register("cluster").
+		AddPersistentFlag("dry-run", "do not apply the changes", Bool, nil).
+		Register("node").
+		Register("add").
+		AddArgument("name", "name of the node", "").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			dryRun, _ := flags["dry-run"].GetBool()
+			context, _ := flags["context"].GetString()
+			executed, result = "add", fmt.Sprint(args["name"].Value, " ", dryRun, " ", context)
+		})
+
+	for _, test := range []struct {
+		args     []string
+		executed string
+		result   string
+	}{
+		{[]string{"cluster", "--dry-run", "node", "add", "node-1"}, "add", "node-1 true default"},
+		{[]string{"--context", "prod", "cluster", "node", "add", "node-1"}, "add", "node-1 false prod"},
+		{[]string{"--context=prod", "cluster", "node", "--dry-run", "add", "node-1"}, "add", "node-1 true prod"},
+		{[]string{"-V", "alpha"}, "alpha", "true"},
+	} {
+		executed, result = "", ""
+		if err := registry.ParseE(test.args); err != nil || executed != test.executed || result != test.result {
+			t.Errorf("%v: unexpected result: %v, %s, %s", test.args, err, executed, result)
+		}
+	}
+
+	// unknown nested command after a flag
+	if err := registry.ParseE([]string{"cluster", "--dry-run", "nod", "add"}); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("expected %v error, got %v", ErrUnknownCommand, err)
+	}
+}
+
+// persistent flags must be accepted by all the sub-commands
+func TestPersistentFlags(t *testing.T) {
+	var verbose, dryRun bool
+
+	registry := NewCommandRegistry()
+	registry.Register(nil).AddPersistentFlag("verbose,V", "display log information", Bool, nil)
+
+	cluster := registry.Register("cluster").AddPersistentFlag("dry-run,d", "do not apply changes", Bool, nil)
+	cluster.
+		Register("deploy").
+		AddFlag("dir,d", "output directory", String, "./out").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			verbose, _ = flags["verbose"].GetBool()
+			dryRun, _ = flags["dry-run"].GetBool()
+		})
+
+	if err := registry.ParseE([]string{"cluster", "deploy", "-V", "--dry-run"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !verbose || !dryRun {
+		t.Errorf("unexpected flag values: %v, %v", verbose, dryRun)
+	}
+
+	// short-name of an inherited flag must not override the short-name of a flag of the command
+	if err := registry.ParseE([]string{"cluster", "deploy", "-d", "./build"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if dryRun {
+		t.Error("expected -d to be the short-name of the --dir flag")
+	}
+
+	// persistent flags must not be accepted by other commands
+	registry.Register("serve").SetAction(func(map[string]ArgValue, map[string]FlagValue) {})
+	if err := registry.ParseE([]string{"serve", "--dry-run"}); !errors.Is(err, ErrUnknownFlag) {
+		t.Errorf("expected %v error, got %v", ErrUnknownFlag, err)
+	}
+}
+
+// aliases must resolve to the same command
+func TestCommandAliases(t *testing.T) {
+	var removed []string
+
+	registry := NewCommandRegistry()
+	registry.
+		Register("remove").
+		AddAlias("rm", "del").
+		AddArgument("name", "name of the component", "").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			removed = append(removed, args["name"].Value)
+		})
+
+	registry.
+		Register("node").
+		Register("list").
+		AddAlias("ls").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			removed = append(removed, "list")
+		})
+
+	for _, args := range [][]string{{"remove", "a"}, {"rm", "b"}, {"del", "c"}, {"node", "ls"}} {
+		if err := registry.ParseE(args); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if strings.Join(removed, ",") != "a,b,c,list" {
+		t.Errorf("unexpected values: %v", removed)
+	}
+
+	if command, err := registry.lookupCommand([]string{"rm"}); err != nil || command.Path() != "remove" {
+		t.Errorf("unexpected lookup result: %v", err)
+	}
+}
+
+// an alias must not conflict with another command
+func TestAliasConflict(t *testing.T) {
+	output, code := runTestProgram(t, nil, "tests/alias-conflict.go")
+
+	if code != ExitCodeFailure || !strings.Contains(output, "Error: alias create of the build command conflicts with another command.") {
+		t.Fail()
+	}
+}
+
+// unknown commands and flags must have suggestions of similar names
+func TestSuggestions(t *testing.T) {
+	registry := newTestRegistry(func(map[string]ArgValue, map[string]FlagValue) {})
+	registry.Register("remove").AddAlias("rm")
+	registry.Register("cluster").Register("node")
+
+	testCases := []struct {
+		args        []string
+		suggestions []string
+	}{
+		{[]string{"craete"}, []string{"create"}},
+		{[]string{"rmove"}, []string{"remove"}},
+		{[]string{"cluster", "nod"}, []string{"node"}},
+		{[]string{"create", "my-service", "--dri", "./out"}, []string{"--dir"}},
+		{[]string{"create", "my-service", "-D", "./out"}, []string{"-d"}},
+		{[]string{"create", "my-service", "-x"}, []string{}},
+	}
+
+	for _, testCase := range testCases {
+		var parseError *ParseError
+		if err := registry.ParseE(testCase.args); !errors.As(err, &parseError) {
+			t.Errorf("%v: expected a parse error, got %v", testCase.args, err)
+			continue
+		}
+
+		if strings.Join(parseError.Suggestions, ",") != strings.Join(testCase.suggestions, ",") {
+			t.Errorf("%v: expected %v suggestions, got %v", testCase.args, testCase.suggestions, parseError.Suggestions)
+		}
+	}
+
+	// suggestions can be disabled
+	registry.SetSuggestionDistance(0)
+
+	var parseError *ParseError
+	if err := registry.ParseE([]string{"craete"}); !errors.As(err, &parseError) || len(parseError.Suggestions) != 0 {
+		t.Errorf("expected no suggestions, got %v", err)
+	}
+}
+
+// test the edit distance between two strings
+func TestEditDistance(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"create", "create", 0},
+		{"craete", "create", 2},
+		{"serve", "server", 1},
+		{"", "build", 5},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, testCase := range testCases {
+		if distance := editDistance(testCase.a, testCase.b); distance != testCase.distance {
+			t.Errorf("%s, %s: expected %d, got %d", testCase.a, testCase.b, testCase.distance, distance)
+		}
+	}
+}
+
+// commands and flags must be displayed in the registration order with built-in commands and flags at the end
+func TestHelpOrder(t *testing.T) {
+	registry := NewCommandRegistry()
+	registry.Register("serve")
+	registry.Register("build")
+	registry.Register(nil).AddFlag("verbose,V", "display log information", Bool, nil).AddFlag("config", "config file", String, "")
+
+	// get names of the sorted commands and flags
+	names := func() (string, string) {
+		commands := make([]string, 0)
+		for _, command := range registry.sortCommands(registry.subCommands(registry.Register(nil))) {
+			commands = append(commands, command.Name())
+		}
+
+		flags := make([]string, 0)
+		for _, flag := range registry.sortFlags(registry.Register(nil).Flags) {
+			flags = append(flags, flag.ClpFlag.Name)
+		}
+
+		return strings.Join(commands, ","), strings.Join(flags, ",")
+	}
+
+	if commands, flags := names(); commands != "serve,build,version,help" || flags != "verbose,config,version,help" {
+		t.Errorf("unexpected order: %s; %s", commands, flags)
+	}
+
+	// alphabetical order
+	registry.SetSortHelp(true)
+
+	if commands, flags := names(); commands != "build,serve,help,version" || flags != "config,verbose,help,version" {
+		t.Errorf("unexpected order: %s; %s", commands, flags)
+	}
+}
+
+// completion scripts must contain commands, aliases and flags
+func TestCompletion(t *testing.T) {
+	registry := newTestRegistry(func(map[string]ArgValue, map[string]FlagValue) {})
+	registry.Register("create").AddFlag("no-clean", "avoid cleanup", Bool, nil)
+	registry.Register("remove").AddAlias("rm").SetShortDescription("removes a component")
+	registry.Register("cluster").Register("node")
+	registry.EnableCompletion()
+
+	for _, shell := range completionShells {
+		var output strings.Builder
+		if err := registry.WriteCompletion(&output, shell); err != nil {
+			t.Fatalf("%s: %v", shell, err)
+		}
+
+		for _, value := range []string{"create", "remove", "rm", "/cluster/node", "dir", "timeout", "no-clean", "completion"} {
+			if !strings.Contains(output.String(), value) {
+				t.Errorf("%s: expected %q in the completion script", shell, value)
+			}
+		}
+
+		// bash does not display descriptions
+		if shell != "bash" && !strings.Contains(output.String(), "removes a component") {
+			t.Errorf("%s: expected a description in the completion script", shell)
+		}
+	}
+
+	// unsupported shell
+	if err := registry.WriteCompletion(ioutil.Discard, "tcsh"); !errors.Is(err, ErrInvalidArgumentValue) {
+		t.Errorf("expected ErrInvalidArgumentValue, got %v", err)
+	}
+
+	// the completion command
+	output, code := runTestProgram(t, nil, "tests/completion.go", "completion", "bash")
+	if code != ExitCodeSuccess || !strings.Contains(output, "complete -o default -F __reactor_complete reactor") {
+		t.Errorf("unexpected output (%d): %s", code, output)
+	}
+}
+
+// completion functions of the arguments and flags must receive the partial command-line arguments
+func TestCompletionCandidates(t *testing.T) {
+	registry := newTestRegistry(func(map[string]ArgValue, map[string]FlagValue) {})
+	registry.EnableCompletion()
+	registry.
+		Register("create").
+		AddFlag("type,t", "type of the component", String, "simple").
+		SetFlagCompletion("type", func(ctx CompletionContext) []Completion {
+			return []Completion{{"simple", "a simple component"}, {"class", ""}}
+		}).
+		SetArgumentCompletion("name", func(ctx CompletionContext) []Completion {
+			return []Completion{{ctx.Flags["type"] + "-" + ctx.ToComplete, ""}}
+		})
+
+	testCases := []struct {
+		args   []string
+		output string
+	}{
+		{[]string{"2", "create", "--type"}, "simple\ta simple component\nclass\n"},
+		{[]string{"3", "create", "-t", "class", "my"}, "class-my\n"},
+		{[]string{"3", "create", "--type=hook", "--dir", "./out"}, ""},
+		{[]string{"1", "create", "x"}, "-x\n"},
+		{[]string{"2", "create", "x", "y"}, ""},
+		{[]string{"1", "help"}, "create\ncompletion\toutputs shell completion script\nversion\tdisplays version number\nhelp\tdisplays usage information\n"},
+		{[]string{"1", "completion", "f"}, "bash\nzsh\nfish\npowershell\n"},
+	}
+
+	for _, testCase := range testCases {
+		var output strings.Builder
+		if err := registry.writeCompletions(&output, testCase.args); err != nil {
+			t.Fatal(err)
+		}
+
+		if output.String() != testCase.output {
+			t.Errorf("%v: expected %q, got %q", testCase.args, testCase.output, output.String())
+		}
+	}
+
+	// the hidden command is accepted by `Parse`
+	output, code := runTestProgram(t, nil, "tests/completion.go", "__complete", "1", "completion", "")
+	if code != ExitCodeSuccess || output != "bash\nzsh\nfish\npowershell\n" {
+		t.Errorf("unexpected output (%d): %s", code, output)
+	}
+}
+
+// flag values must be converted to the data types of the flags
+func TestFlagDataTypes(t *testing.T) {
+	var values map[string]FlagValue
+
+	registry := NewCommandRegistry()
+	registry.
+		Register("serve").
+		AddFlag("ratio", "ratio", Float, 0.5).
+		AddFlag("workers", "number of workers", Uint, uint(4)).
+		AddFlag("size", "size in bytes", Int64, int64(1<<40)).
+		AddFlag("timeout", "timeout", Duration, time.Minute).
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			values = flags
+		})
+
+	// default values
+	if err := registry.ParseE([]string{"serve"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ratio, _ := values["ratio"].GetFloat()
+	workers, _ := values["workers"].GetUint()
+	size, _ := values["size"].GetInt64()
+	timeout, _ := values["timeout"].GetDuration()
+	if ratio != 0.5 || workers != 4 || size != 1<<40 || timeout != time.Minute {
+		t.Errorf("unexpected default values: %v, %v, %v, %v", ratio, workers, size, timeout)
+	}
+
+	// user values
+	if err := registry.ParseE([]string{"serve", "--ratio=1.25", "--workers", "8", "--size=2", "--timeout", "1m30s"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ratio, _ = values["ratio"].GetFloat()
+	workers, _ = values["workers"].GetUint()
+	size, _ = values["size"].GetInt64()
+	timeout, _ = values["timeout"].GetDuration()
+	if ratio != 1.25 || workers != 8 || size != 2 || timeout != 90*time.Second {
+		t.Errorf("unexpected values: %v, %v, %v, %v", ratio, workers, size, timeout)
+	}
+
+	if _, err := values["ratio"].GetDuration(); err == nil {
+		t.Error("expected a conversion error")
+	}
+
+	// invalid values
+	testCases := []struct {
+		args    []string
+		message string
+	}{
+		{[]string{"serve", "--ratio", "x"}, "value of the --ratio flag must be a number"},
+		{[]string{"serve", "--workers=1e3"}, "value of the --workers flag must be a non-negative integer"},
+		{[]string{"serve", "--size", "1.5"}, "value of the --size flag must be an integer"},
+		{[]string{"serve", "--timeout", "90"}, "value of the --timeout flag must be a duration (e.g. 1m30s)"},
+	}
+
+	for _, testCase := range testCases {
+		if err := registry.ParseE(testCase.args); !errors.Is(err, ErrInvalidFlagValue) || err.Error() != testCase.message {
+			t.Errorf("%v: unexpected error %v", testCase.args, err)
+		}
+	}
+}
+
+// repeatable flags must accumulate the values of all the occurrences
+func TestRepeatableFlags(t *testing.T) {
+	var values map[string]FlagValue
+
+	registry := NewCommandRegistry()
+	registry.Register(nil).AddPersistentFlag("exclude,e", "excluded paths", StringSlice, []string{"vendor"})
+	registry.
+		Register("build").
+		AddFlag("include,i", "included paths", StringSlice, nil).
+		AddFlag("port", "ports", IntSlice, []int{}).
+		AddFlag("dir", "output directory", String, "./out").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			values = flags
+		})
+
+	get := func() (string, string, string, string) {
+		include, _ := values["include"].GetStringSlice()
+		exclude, _ := values["exclude"].GetStringSlice()
+		ports, _ := values["port"].GetIntSlice()
+		dir, _ := values["dir"].GetString()
+
+		return strings.Join(include, "|"), strings.Join(exclude, "|"), fmt.Sprint(ports), dir
+	}
+
+	if err := registry.ParseE([]string{"build", "--include", "a", "-i=b,c", "--dir", "./dist", "--port=80", "--port", "443", "-e", "x", "--include", "d"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if include, exclude, ports, dir := get(); include != "a|b|c|d" || exclude != "x" || ports != "[80 443]" || dir != "./dist" {
+		t.Errorf("unexpected values: %s, %s, %s, %s", include, exclude, ports, dir)
+	}
+
+	// default values
+	if err := registry.ParseE([]string{"build", "-i", "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if include, exclude, ports, _ := get(); include != "a" || exclude != "vendor" || ports != "[]" {
+		t.Errorf("unexpected values: %s, %s, %s", include, exclude, ports)
+	}
+
+	// values are not split without a delimiter
+	registry.Register("build").SetFlagDelimiter("include", "")
+
+	if err := registry.ParseE([]string{"build", "-i", "a,b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if include, _, _, _ := get(); include != "a,b" {
+		t.Errorf("unexpected value: %s", include)
+	}
+
+	// invalid values
+	if err := registry.ParseE([]string{"build", "-i", "a", "--port", "80,http"}); !errors.Is(err, ErrInvalidFlagValue) || err.Error() != "value of the --port flag must be a list of integers" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := registry.ParseE([]string{"build"}); !errors.Is(err, ErrMissingFlag) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// a repeatable flag without a value (at the end or followed by another flag) or with an empty value
+	for _, test := range []struct {
+		args []string
+		name string
+	}{
+		{[]string{"build", "--include"}, "include"},
+		{[]string{"build", "--include", "--dir", "./dist"}, "include"},
+		{[]string{"build", "-i", "a", "--port"}, "port"},
+		{[]string{"build", "-i", "a", "--port="}, "port"},
+		{[]string{"build", "-i", "a", "--port", ""}, "port"},
+	} {
+		if err := registry.ParseE(test.args); !errors.Is(err, ErrMissingFlag) || err.Error() != "value of the --"+test.name+" flag can not be empty" {
+			t.Errorf("unexpected error (%v): %v", test.args, err)
+		}
+	}
+
+	// empty values do not satisfy a required flag
+	for _, args := range [][]string{{"build", "--include", ""}, {"build", "-i="}} {
+		if err := registry.ParseE(args); !errors.Is(err, ErrMissingFlag) || err.Error() != "value of the --include flag can not be empty" {
+			t.Errorf("unexpected error (%v): %v", args, err)
+		}
+	}
+}
+
+// map flags must parse key=value pairs of all the occurrences
+func TestMapFlags(t *testing.T) {
+	var labels map[string]string
+
+	registry := NewCommandRegistry()
+	registry.
+		Register("deploy").
+		AddFlag("label,l", "labels", StringMap, map[string]string{"env": "dev"}).
+		AddFlag("set", "values", StringMap, map[string]string{}).
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			labels, _ = flags["label"].GetStringMap()
+			values, _ := flags["set"].GetStringMap()
+			for key, value := range values {
+				labels["set:"+key] = value
+			}
+		})
+
+	testCases := []struct {
+		args   []string
+		labels string
+	}{
+		{[]string{"deploy"}, "map[env:dev]"},
+		{[]string{"deploy", "--label", "env=prod", "-l=team=core", "--set", "a.b=1,2", "--label", "env=stage"}, "map[env:stage set:a.b:1,2 team:core]"},
+		{[]string{"deploy", "--label", "empty="}, "map[empty:]"},
+	}
+
+	for _, testCase := range testCases {
+		if err := registry.ParseE(testCase.args); err != nil {
+			t.Fatal(err)
+		}
+
+		if fmt.Sprint(labels) != testCase.labels {
+			t.Errorf("%v: expected %s, got %v", testCase.args, testCase.labels, labels)
+		}
+	}
+
+	// malformed pairs
+	for _, value := range []string{"env", "=prod"} {
+		if err := registry.ParseE([]string{"deploy", "--label", value}); !errors.Is(err, ErrInvalidFlagValue) || err.Error() != "value of the --label flag must be a list of key=value pairs ("+value+" is not a key=value pair)" {
+			t.Errorf("%s: unexpected error %v", value, err)
+		}
+	}
+
+	// empty values do not satisfy a required map flag
+	registry.
+		Register("tag").
+		AddFlag("label,l", "labels", StringMap, nil).
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {})
+
+	for _, args := range [][]string{{"tag", "--label", ""}, {"tag", "-l="}, {"tag", "--label"}} {
+		if err := registry.ParseE(args); !errors.Is(err, ErrMissingFlag) || err.Error() != "value of the --label flag can not be empty" {
+			t.Errorf("%v: unexpected error %v", args, err)
+		}
+	}
+
+	// pairs are split by the delimiter
+	registry.Register("deploy").SetFlagDelimiter("label", ",")
+
+	if err := registry.ParseE([]string{"deploy", "--label", "a=1,b=2"}); err != nil || fmt.Sprint(labels) != "map[a:1 b:2]" {
+		t.Errorf("unexpected result: %v, %v", err, labels)
+	}
+}
+
+// values of the flags and arguments with choices must be one of the choices
+func TestChoices(t *testing.T) {
+	registry := NewCommandRegistry()
+	registry.EnableCompletion()
+	registry.
+		Register("create").
+		AddArgument("kind", "kind of the component", "").
+		AddArgument("files...", "files", "").
+		AddFlag("type,t", "type of the component", String, "simple").
+		AddFlag("tag", "tags", StringSlice, []string{}).
+		SetArgumentChoices("kind", "service", "library").
+		SetArgumentChoices("files...", "a.txt", "b.txt").
+		SetFlagChoices("type", "simple", "class", "hook").
+		SetFlagChoices("tag", "x", "y").
+		SetAction(func(map[string]ArgValue, map[string]FlagValue) {})
+
+	testCases := []struct {
+		args    []string
+		kind    error
+		message string
+	}{
+		{[]string{"create", "service", "a.txt", "b.txt", "-t", "hook", "--tag", "x,y"}, nil, ""},
+		{[]string{"create", "library"}, nil, ""},
+		{[]string{"create", "app"}, ErrInvalidArgumentValue, "value of the kind argument must be one of service, library"},
+		{[]string{"create", "service", "a.txt", "c.txt"}, ErrInvalidArgumentValue, "value of the files argument must be one of a.txt, b.txt"},
+		{[]string{"create", "service", "--type=widget"}, ErrInvalidFlagValue, "value of the --type flag must be one of simple, class, hook"},
+		{[]string{"create", "service", "--tag", "x", "--tag", "z"}, ErrInvalidFlagValue, "values of the --tag flag must be one of x, y"},
+	}
+
+	for _, testCase := range testCases {
+		err := registry.ParseE(testCase.args)
+		if testCase.kind == nil && err != nil {
+			t.Errorf("%v: unexpected error %v", testCase.args, err)
+		} else if testCase.kind != nil && (!errors.Is(err, testCase.kind) || err.Error() != testCase.message) {
+			t.Errorf("%v: unexpected error %v", testCase.args, err)
+		}
+	}
+
+	// choices are completion candidates
+	var output strings.Builder
+	if err := registry.writeCompletions(&output, []string{"2", "create", "--type", ""}); err != nil || output.String() != "simple\nclass\nhook\n" {
+		t.Errorf("unexpected candidates: %v, %q", err, output.String())
+	}
+}
+
+// urlValue is a custom flag value for the tests
+type urlValue struct {
+	url *url.URL
+}
+
+func (v *urlValue) Set(value string) error {
+	u, err := url.ParseRequestURI(value)
+	if err != nil {
+		return err
+	}
+
+	v.url = u
+	return nil
+}
+
+func (v *urlValue) String() string {
+	if v.url == nil {
+		return ""
+	}
+
+	return v.url.String()
+}
+
+func (v *urlValue) Type() string {
+	return "url"
+}
+
+// custom flag values must be set by the `Value` implementations
+func TestCustomFlagValues(t *testing.T) {
+	var endpoint, proxy string
+
+	defaultEndpoint := &urlValue{}
+	defaultEndpoint.Set("https://example.com/api")
+
+	registry := NewCommandRegistry()
+	registry.
+		Register("fetch").
+		AddFlagValue("endpoint,e", "API endpoint", defaultEndpoint).
+		AddFlagValue("proxy", "proxy server", &urlValue{}).
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			endpoint = flags["endpoint"].Value.(*urlValue).url.Host
+			proxy = flags["proxy"].Value.(Value).String()
+		})
+
+	if err := registry.ParseE([]string{"fetch", "--proxy", "http://localhost:3128", "-e", "https://test.com/"}); err != nil || endpoint != "test.com" || proxy != "http://localhost:3128" {
+		t.Errorf("unexpected result: %v, %s, %s", err, endpoint, proxy)
+	}
+
+	// default-value is restored
+	if err := registry.ParseE([]string{"fetch", "--proxy", "http://localhost:3128"}); err != nil || endpoint != "example.com" {
+		t.Errorf("unexpected result: %v, %s", err, endpoint)
+	}
+
+	// a flag without a default-value is required
+	if err := registry.ParseE([]string{"fetch"}); !errors.Is(err, ErrMissingFlag) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// invalid value
+	if err := registry.ParseE([]string{"fetch", "--proxy", "localhost"}); !errors.Is(err, ErrInvalidFlagValue) || err.Error() != "value of the --proxy flag must be a valid url" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// argument values must be converted to the data types of the arguments
+func TestTypedArguments(t *testing.T) {
+	var values map[string]ArgValue
+
+	registry := NewCommandRegistry()
+	registry.
+		Register("scale").
+		AddTypedArgument("replicas", "number of replicas", Int, nil).
+		AddArgumentValue("endpoint", "API endpoint", &urlValue{}).
+		AddTypedArgument("delays...", "delays between the replicas", Duration, nil).
+		AddFlag("dir,d", "output directory", String, "./out").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			values = args
+		})
+
+	registry.
+		Register("copy").
+		AddArgument("files...", "files", "").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			values = args
+		})
+
+	if err := registry.ParseE([]string{"scale", "3", "-d", "./dist", "http://localhost/", "1s", "--dir=./out", "1m"}); err != nil {
+		t.Fatal(err)
+	}
+
+	replicas, _ := values["replicas"].GetInt()
+	endpoint := values["endpoint"].TypedValue.(*urlValue).url.Host
+	delays := values["delays"].TypedValue.([]time.Duration)
+	if replicas != 3 || endpoint != "localhost" || fmt.Sprint(delays) != "[1s 1m0s]" || values["delays"].Value != "1s,1m" {
+		t.Errorf("unexpected values: %v, %v, %v", replicas, endpoint, delays)
+	}
+
+	if _, err := values["delays"].GetDuration(); err == nil {
+		t.Error("expected a conversion error")
+	}
+
+	// values of a variadic argument can contain commas
+	if err := registry.ParseE([]string{"copy", "a,b.txt", "c.txt"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if files := values["files"].GetStrings(); len(files) != 2 || files[0] != "a,b.txt" || files[1] != "c.txt" {
+		t.Errorf("unexpected values: %q", files)
+	}
+
+	// invalid values
+	testCases := []struct {
+		args    []string
+		message string
+	}{
+		{[]string{"scale", "three", "http://localhost/"}, "value of the replicas argument must be an integer"},
+		{[]string{"scale", "3", "localhost"}, "value of the endpoint argument must be a valid url"},
+		{[]string{"scale", "3", "http://localhost/", "1s", "soon"}, "value of the delays argument must be a duration (e.g. 1m30s)"},
+	}
+
+	for _, testCase := range testCases {
+		if err := registry.ParseE(testCase.args); !errors.Is(err, ErrInvalidArgumentValue) || err.Error() != testCase.message {
+			t.Errorf("%v: unexpected error %v", testCase.args, err)
+		}
+	}
+}
+
+// number of values of a variadic argument must satisfy its arity
+func TestArgumentArity(t *testing.T) {
+	registry := NewCommandRegistry()
+	registry.
+		Register("copy").
+		AddArgument("files...", "files", "").
+		SetArgumentArity("files", Between(1, 3)).
+		SetAction(func(map[string]ArgValue, map[string]FlagValue) {})
+
+	testCases := []struct {
+		args    []string
+		kind    error
+		message string
+	}{
+		{[]string{"copy", "a"}, nil, ""},
+		{[]string{"copy", "a", "b", "c"}, nil, ""},
+		{[]string{"copy"}, ErrMissingArgument, "expected 1 to 3 values of the files argument, got 0"},
+		{[]string{"copy", "a", "b", "c", "d"}, ErrTooManyArguments, "expected 1 to 3 values of the files argument, got 4"},
+	}
+
+	for _, testCase := range testCases {
+		err := registry.ParseE(testCase.args)
+		if testCase.kind == nil && err != nil {
+			t.Errorf("%v: unexpected error %v", testCase.args, err)
+		} else if testCase.kind != nil && (!errors.Is(err, testCase.kind) || err.Error() != testCase.message) {
+			t.Errorf("%v: unexpected error %v", testCase.args, err)
+		}
+	}
+
+	if !registry.Register("copy").Args["files"].IsRequired {
+		t.Error("expected a required argument")
+	}
+
+	// descriptions of the arities
+	for arity, desc := range map[Arity]string{
+		AtLeast(1):    "at least 1 value",
+		AtMost(2):     "at most 2 values",
+		Exactly(1):    "exactly 1 value",
+		Between(2, 4): "2 to 4 values",
+	} {
+		if arity.String() != desc {
+			t.Errorf("expected %s, got %s", desc, arity)
+		}
+	}
+}
+
+// flag values must be resolved from the command-line, the environment variables and the default-values
+func TestFlagEnv(t *testing.T) {
+	var values map[string]FlagValue
+
+	registry := NewCommandRegistry()
+	registry.SetEnvPrefix("REACTOR")
+	registry.Register(nil).AddPersistentFlag("verbose,V", "display log information", Bool, nil)
+	registry.
+		Register("create").
+		AddFlag("dir,d", "output directory", String, nil).
+		AddFlag("timeout", "timeout", Int, 60).
+		AddFlag("tag", "tags", StringSlice, []string{}).
+		SetFlagEnv("timeout", "TEST_TIMEOUT", "TEST_TIMEOUT_SECONDS").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			values = flags
+		})
+
+	env := map[string]string{
+		"REACTOR_CREATE_DIR":   "./env",
+		"REACTOR_VERBOSE":      "1",
+		"REACTOR_CREATE_TAG":   "a,b",
+		"TEST_TIMEOUT_SECONDS": "30",
+	}
+
+	for name, value := range env {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	get := func() string {
+		dir, _ := values["dir"].GetString()
+		timeout, _ := values["timeout"].GetInt()
+		verbose, _ := values["verbose"].GetBool()
+		tags, _ := values["tag"].GetStringSlice()
+
+		return fmt.Sprint(dir, " ", timeout, " ", verbose, " ", tags)
+	}
+
+	// environment variables (a required flag is satisfied by an environment variable)
+	if err := registry.ParseE([]string{"create"}); err != nil || get() != "./env 30 true [a b]" {
+		t.Errorf("unexpected result: %v, %s", err, get())
+	}
+
+	// command-line values take precedence
+	if err := registry.ParseE([]string{"create", "-d", "./cli", "--timeout", "10", "--tag", "c"}); err != nil || get() != "./cli 10 true [c]" {
+		t.Errorf("unexpected result: %v, %s", err, get())
+	}
+
+	// first environment variable takes precedence and the default-value is used without environment variables
+	os.Setenv("TEST_TIMEOUT", "20")
+	os.Unsetenv("TEST_TIMEOUT_SECONDS")
+	defer os.Unsetenv("TEST_TIMEOUT")
+
+	if err := registry.ParseE([]string{"create"}); err != nil || get() != "./env 20 true [a b]" {
+		t.Errorf("unexpected result: %v, %s", err, get())
+	}
+
+	os.Unsetenv("TEST_TIMEOUT")
+	if err := registry.ParseE([]string{"create"}); err != nil || get() != "./env 60 true [a b]" {
+		t.Errorf("unexpected result: %v, %s", err, get())
+	}
+
+	// invalid value
+	os.Setenv("REACTOR_VERBOSE", "sure")
+	if err := registry.ParseE([]string{"create"}); !errors.Is(err, ErrInvalidFlagValue) || err.Error() != "value of the REACTOR_VERBOSE environment variable must be a boolean" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// names of the environment variables
+	if names := registry.Register("create").Flags["timeout"].EnvNames(); strings.Join(names, ",") != "TEST_TIMEOUT,TEST_TIMEOUT_SECONDS,REACTOR_CREATE_TIMEOUT" {
+		t.Errorf("unexpected names: %v", names)
+	}
+
+	if names := registry.Register("create").Flags["help"].EnvNames(); len(names) != 0 {
+		t.Errorf("unexpected names: %v", names)
+	}
+}
+
+// flag values must be resolved from the configuration files (JSON, INI and TOML) after the environment variables
+func TestConfigFile(t *testing.T) {
+	var values map[string]FlagValue
+
+	dir, err := ioutil.TempDir("", "commando")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"reactor.json": `{"verbose": true, "create": {"dir": "./json", "timeout": 30, "tag": ["a", "b"], "label": {"env": "prod"}}}`,
+		"reactor.ini":  "verbose = true\n; comment\n[create]\ndir = \"./ini\"\ntimeout = 30\ntag = a\ntag = b\nlabel = env=prod\n",
+		"reactor.toml": "verbose = true # comment\n\n[create]\ndir = './toml'\ntimeout = 30\ntag = [\n  \"a\",\n  \"b\",\n]\nlabel = { env = \"prod\" }\n",
+		"yes.ini":      "verbose = yes\n[create]\ndir = ./ini\n",
+		"off.ini":      "verbose = Off\n[create]\ndir = ./ini\n",
+		"invalid.json": `{"create": {"dir": "./json", "timeout": "soon"}}`,
+		"broken.toml":  "[create]\ndir = \"./toml\n",
+	}
+
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	registry := NewCommandRegistry()
+	registry.SetEnvPrefix("REACTOR").SetConfigFile(filepath.Join(dir, "missing.json")).SetConfigFlag("config,c", "configuration file")
+	registry.Register(nil).AddPersistentFlag("verbose,V", "display log information", Bool, nil)
+	registry.
+		Register("create").
+		AddFlag("dir,d", "output directory", String, nil).
+		AddFlag("timeout", "timeout", Int, 60).
+		AddFlag("tag", "tags", StringSlice, []string{}).
+		AddFlag("label", "labels", StringMap, map[string]string{}).
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			values = flags
+		})
+
+	get := func() string {
+		dir, _ := values["dir"].GetString()
+		timeout, _ := values["timeout"].GetInt()
+		verbose, _ := values["verbose"].GetBool()
+		tags, _ := values["tag"].GetStringSlice()
+		labels, _ := values["label"].GetStringMap()
+
+		return fmt.Sprint(dir, " ", timeout, " ", verbose, " ", tags, " ", labels)
+	}
+
+	// values of the configuration files
+	for _, format := range []string{"json", "ini", "toml"} {
+		path := filepath.Join(dir, "reactor."+format)
+		if err := registry.ParseE([]string{"create", "--config", path}); err != nil || get() != "./"+format+" 30 true [a b] map[env:prod]" {
+			t.Errorf("unexpected result (%s): %v, %s", format, err, get())
+		}
+	}
+
+	// INI boolean values
+	for name, expected := range map[string]string{"yes.ini": "./ini 60 true [] map[]", "off.ini": "./ini 60 false [] map[]"} {
+		if err := registry.ParseE([]string{"create", "--config", filepath.Join(dir, name)}); err != nil || get() != expected {
+			t.Errorf("unexpected result (%s): %v, %s", name, err, get())
+		}
+	}
+
+	// command-line values and environment variables take precedence
+	os.Setenv("REACTOR_CREATE_TIMEOUT", "10")
+	defer os.Unsetenv("REACTOR_CREATE_TIMEOUT")
+
+	if err := registry.ParseE([]string{"create", "-c", filepath.Join(dir, "reactor.json"), "-d", "./cli"}); err != nil || get() != "./cli 10 true [a b] map[env:prod]" {
+		t.Errorf("unexpected result: %v, %s", err, get())
+	}
+
+	os.Unsetenv("REACTOR_CREATE_TIMEOUT")
+
+	// missing default configuration file is ignored
+	if err := registry.ParseE([]string{"create", "-d", "./cli"}); err != nil || get() != "./cli 60 false [] map[]" {
+		t.Errorf("unexpected result: %v, %s", err, get())
+	}
+
+	// path of the configuration file from an environment variable
+	os.Setenv("REACTOR_CONFIG", filepath.Join(dir, "reactor.toml"))
+	defer os.Unsetenv("REACTOR_CONFIG")
+
+	if err := registry.ParseE([]string{"create"}); err != nil || get() != "./toml 30 true [a b] map[env:prod]" {
+		t.Errorf("unexpected result: %v, %s", err, get())
+	}
+
+	os.Unsetenv("REACTOR_CONFIG")
+
+	// invalid value
+	if err := registry.ParseE([]string{"create", "-c", filepath.Join(dir, "invalid.json")}); !errors.Is(err, ErrInvalidFlagValue) || err.Error() != "value of the create.timeout key of the config file must be an integer" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// invalid and missing configuration files
+	for _, name := range []string{"broken.toml", "missing.json", "reactor.yaml"} {
+		if err := registry.ParseE([]string{"create", "-c", filepath.Join(dir, name)}); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("unexpected error (%s): %v", name, err)
+		}
+	}
+}
+
+// argument and flag values must report whether they are provided by the user and their sources
+func TestValueSources(t *testing.T) {
+	var argValues map[string]ArgValue
+	var flagValues map[string]FlagValue
+
+	registry := NewCommandRegistry()
+	registry.SetEnvPrefix("REACTOR")
+	registry.
+		Register("create").
+		AddArgument("name", "name of the component", "").
+		AddArgument("version", "version of the component", "1.0.0").
+		AddFlag("dir,d", "output directory", String, "./out").
+		AddFlag("timeout", "timeout", Int, 60).
+		AddFlag("tag", "tags", StringSlice, []string{}).
+		AddFlag("verbose", "display log information", Bool, nil).
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			argValues, flagValues = args, flags
+		})
+
+	os.Setenv("REACTOR_CREATE_TAG", "a")
+	defer os.Unsetenv("REACTOR_CREATE_TAG")
+
+	if err := registry.ParseE([]string{"create", "button", "--timeout", "60"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := fmt.Sprint(
+		argValues["name"].Source(), " ", argValues["version"].Source(), " ",
+		flagValues["dir"].Source(), " ", flagValues["timeout"].Source(), " ",
+		flagValues["tag"].Source(), " ", flagValues["verbose"].Source(),
+	)
+
+	if sources != "command-line default default command-line env default" {
+		t.Errorf("unexpected sources: %s", sources)
+	}
+
+	// a value equal to the default-value is still set by the user
+	if !flagValues["timeout"].IsSet() || flagValues["dir"].IsSet() || !argValues["name"].IsSet() || argValues["version"].IsSet() {
+		t.Errorf("unexpected IsSet values")
+	}
+}
+
+// constraints of the flag groups must be checked after the flag values are resolved
+func TestFlagGroups(t *testing.T) {
+	var values map[string]FlagValue
+
+	registry := NewCommandRegistry()
+	registry.
+		Register("export").
+		AddFlag("file,f", "output file", String, nil).
+		AddFlag("stdout", "write to the standard output", Bool, nil).
+		AddFlag("width,W", "image width", Int, 0).
+		AddFlag("height,H", "image height", Int, 0).
+		MutuallyExclusive("file", "stdout").
+		OneRequired("file", "stdout").
+		RequiredTogether("width", "height").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			values = flags
+		})
+
+	// flags of a group are not required individually
+	if flag := registry.Register("export").Flags["file"]; flag.IsRequired {
+		t.Errorf("--file flag must not be required")
+	}
+
+	for _, test := range []struct {
+		args    []string
+		kind    error
+		message string
+	}{
+		{[]string{"export"}, ErrMissingFlag, "one of the --file, --stdout flags is required"},
+		{[]string{"export", "-f", "out.json", "--stdout"}, ErrConflictingFlags, "--file and --stdout flags can not be used together"},
+		{[]string{"export", "--stdout", "-H", "600"}, ErrMissingFlag, "--width flag is required with the --height flag"},
+	} {
+		if err := registry.ParseE(test.args); !errors.Is(err, test.kind) || err.Error() != test.message {
+			t.Errorf("unexpected error (%v): %v", test.args, err)
+		}
+	}
+
+	// optional flags without a default-value have the zero value
+	if err := registry.ParseE([]string{"export", "-f", "out.json", "-W", "800", "-H", "600"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if result := fmt.Sprint(values["file"].Value, " ", values["stdout"].Value, " ", values["width"].Value); result != "out.json false 800" {
+		t.Errorf("unexpected result: %s", result)
+	}
+
+	if err := registry.ParseE([]string{"export", "--stdout"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if result := fmt.Sprintf("%q %v %v", values["file"].Value, values["stdout"].Value, values["width"].Value); result != `"" true 0` {
+		t.Errorf("unexpected result: %s", result)
+	}
+
+	// descriptions of the constraints (displayed in the usage)
+	if s := fmt.Sprint(registry.Register("export").FlagGroups); s != "[only one of --file, --stdout can be provided one of --file, --stdout must be provided --width, --height must be provided together]" {
+		t.Errorf("unexpected constraints: %s", s)
+	}
+
+	// flags of a required-together group remain required
+	registry.
+		Register("login").
+		AddFlag("user,u", "user name", String, nil).
+		AddFlag("password,p", "password", String, nil).
+		RequiredTogether("user", "password").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {})
+
+	if err := registry.ParseE([]string{"login"}); !errors.Is(err, ErrMissingFlag) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := registry.ParseE([]string{"login", "-u", "admin", "-p", "secret"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// validation rules must be checked before the action function and reported as usage errors
+func TestRules(t *testing.T) {
+	executed := false
+
+	registry := NewCommandRegistry()
+	registry.
+		Register("create").
+		AddArgument("name", "name of the component", "").
+		AddArgument("version", "version of the component", "latest").
+		AddFlag("type,t", "content type", String, "simple").
+		AddFlag("dir,d", "output directory", String, "./out").
+		AddFlag("publish", "publish the component", Bool, nil).
+		AddRule(func(args map[string]ArgValue, flags map[string]FlagValue) error {
+			if flags["type"].Value == "class" && !flags["dir"].IsSet() {
+				return errors.New("--dir flag is required for the class type")
+			}
+
+			return nil
+		}).
+		AddRule(func(args map[string]ArgValue, flags map[string]FlagValue) error {
+			if publish, _ := flags["publish"].GetBool(); publish && !args["version"].IsSet() {
+				return newParseError(ErrMissingArgument, "version", nil, "version argument is required to publish the component")
+			}
+
+			return nil
+		}).
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			executed = true
+		})
+
+	for _, test := range []struct {
+		args    []string
+		kind    error
+		message string
+	}{
+		{[]string{"create", "button", "-t", "class"}, ErrRuleViolation, "--dir flag is required for the class type"},
+		{[]string{"create", "button", "--publish"}, ErrMissingArgument, "version argument is required to publish the component"},
+	} {
+		if err := registry.ParseE(test.args); !errors.Is(err, test.kind) || err.Error() != test.message || executed {
+			t.Errorf("unexpected error (%v): %v", test.args, err)
+		}
+	}
+
+	if err := registry.ParseE([]string{"create", "button", "1.0.0", "-t", "class", "-d", "./src", "--publish"}); err != nil || !executed {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// rule errors are usage errors
+	if code := registry.exitCode(registry.ParseE([]string{"create", "button", "-t", "class"})); code != ExitCodeUsage {
+		t.Errorf("unexpected exit code: %d", code)
+	}
+}
+
+// validators of the flags and arguments must be called with the converted values
+func TestValidators(t *testing.T) {
+	var values map[string]FlagValue
+
+	dir, err := ioutil.TempDir("", "commando")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "reactor.json")
+	if err := ioutil.WriteFile(file, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := NewCommandRegistry()
+	registry.
+		Register("serve").
+		AddArgument("files...", "files to serve", "").
+		AddFlag("port,p", "port", Int, 8080).
+		AddFlag("name", "name of the server", String, "app").
+		AddFlag("ids", "ids", IntSlice, []int{}).
+		AddFlag("dir,d", "output directory", String, "./missing").
+		AddFlag("timeout", "timeout", Duration, time.Minute).
+		SetFlagValidators("port", Range(1, 65535)).
+		SetFlagValidators("name", NonEmpty(), Regex(`^[a-z]+$`)).
+		SetFlagValidators("ids", Range(1, 10)).
+		SetFlagValidators("dir", ExistingDir(), WritableDir()).
+		SetFlagValidators("timeout", Range(0, float64(time.Hour))).
+		SetArgumentValidators("files", ExistingFile()).
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			values = flags
+		})
+
+	for _, test := range []struct {
+		args    []string
+		kind    error
+		message string
+	}{
+		{[]string{"serve", "-p", "70000"}, ErrInvalidFlagValue, "value of the --port flag must be between 1 and 65535 (got 70000)"},
+		{[]string{"serve", "--name", " "}, ErrInvalidFlagValue, "value of the --name flag must not be empty"},
+		{[]string{"serve", "--name", "App"}, ErrInvalidFlagValue, "value of the --name flag must match ^[a-z]+$ (got App)"},
+		{[]string{"serve", "--ids", "3", "--ids", "11"}, ErrInvalidFlagValue, "value of the --ids flag must be between 1 and 10 (got 11)"},
+		{[]string{"serve", "-d", file}, ErrInvalidFlagValue, "value of the --dir flag must be an existing directory (got " + file + ")"},
+		{[]string{"serve", "--timeout", "2h"}, ErrInvalidFlagValue, "value of the --timeout flag must be between 0 and 3.6e+12 (got 2h0m0s)"},
+		{[]string{"serve", file, dir}, ErrInvalidArgumentValue, "value of the files argument must be an existing file (got " + dir + ")"},
+	} {
+		if err := registry.ParseE(test.args); !errors.Is(err, test.kind) || err.Error() != test.message {
+			t.Errorf("unexpected error (%v): %v", test.args, err)
+		}
+	}
+
+	// default-values are not validated
+	if err := registry.ParseE([]string{"serve", file, "-d", dir, "--ids", "3"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if ids, _ := values["ids"].GetIntSlice(); fmt.Sprint(ids) != "[3]" {
+		t.Errorf("unexpected result: %v", ids)
+	}
+
+	if err := registry.ParseE([]string{"serve"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// descriptions of the validators (displayed in the usage)
+	if s := fmt.Sprint(registry.Register("serve").Flags["name"].Validators); s != "[non-empty matches ^[a-z]+$]" {
+		t.Errorf("unexpected descriptions: %s", s)
+	}
+
+	// values of a variadic argument and a repeatable flag are validated even if there are no values
+	registry.
+		Register("create").
+		AddArgument("name", "name of the component", "").
+		AddArgument("files...", "files of the component", "").
+		AddFlag("tag", "tags", StringSlice, []string{}).
+		SetArgumentValidators("files", NonEmpty()).
+		SetFlagValidators("tag", NonEmpty()).
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {})
+
+	if err := registry.ParseE([]string{"create", "name"}); !errors.Is(err, ErrInvalidArgumentValue) || err.Error() != "value of the files argument must not be empty" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := registry.ParseE([]string{"create", "name", file}); !errors.Is(err, ErrInvalidFlagValue) || err.Error() != "value of the --tag flag must not be empty" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := registry.ParseE([]string{"create", "name", file, "--tag", "ui"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// pre-run and post-run hooks of the parent commands and the middleware must wrap the action function
+func TestHooks(t *testing.T) {
+	calls := make([]string, 0)
+
+	hook := func(name string, err error) ActionFunc {
+		return func(ctx context.Context, args map[string]ArgValue, flags map[string]FlagValue) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+
+	middleware := func(name string) Middleware {
+		return func(next ActionFunc) ActionFunc {
+			return func(ctx context.Context, args map[string]ArgValue, flags map[string]FlagValue) error {
+				calls = append(calls, name+":before")
+				err := next(ctx, args, flags)
+				calls = append(calls, name+":after")
+
+				return err
+			}
+		}
+	}
+
+	registry := NewCommandRegistry()
+	registry.Use(middleware("timer"), middleware("telemetry"))
+	registry.Register(nil).SetPreRun(hook("root:pre", nil)).SetPostRun(hook("root:post", nil))
+
+	cluster := registry.Register("cluster").SetPreRun(hook("cluster:pre", nil)).SetPostRun(hook("cluster:post", nil))
+	cluster.
+		Register("add").
+		AddFlag("fail", "fail the action", Bool, nil).
+		SetActionE(func(ctx context.Context, args map[string]ArgValue, flags map[string]FlagValue) error {
+			calls = append(calls, "add")
+			if fail, _ := flags["fail"].GetBool(); fail {
+				return errors.New("failed")
+			}
+
+			return nil
+		})
+
+	// hooks of the parent commands are executed for a sub-command
+	if err := registry.ParseE([]string{"cluster", "add"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := strings.Join(calls, " "); s != "timer:before telemetry:before root:pre cluster:pre add cluster:post root:post telemetry:after timer:after" {
+		t.Errorf("unexpected calls: %s", s)
+	}
+
+	// post-run hooks are not executed if the action fails
+	calls = calls[:0]
+	if err := registry.ParseE([]string{"cluster", "add", "--fail"}); err == nil || err.Error() != "failed" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if s := strings.Join(calls, " "); s != "timer:before telemetry:before root:pre cluster:pre add telemetry:after timer:after" {
+		t.Errorf("unexpected calls: %s", s)
+	}
+
+	// an error of a pre-run hook stops the execution (a hook is not replaced once registered)
+	calls = calls[:0]
+	cluster.
+		Register("remove").
+		SetPreRun(hook("remove:pre", errors.New("no credentials"))).
+		SetPreRun(hook("remove:other", nil)).
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {
+			calls = append(calls, "remove")
+		})
+
+	if err := registry.ParseE([]string{"cluster", "remove"}); err == nil || err.Error() != "no credentials" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if s := strings.Join(calls, " "); s != "timer:before telemetry:before root:pre cluster:pre remove:pre telemetry:after timer:after" {
+		t.Errorf("unexpected calls: %s", s)
+	}
+
+	// the hooks of an `Action` function receive the same context as the hooks of an `ActionE` function
+	var contexts []context.Context
+	registry.
+		Register("status").
+		SetPreRun(func(ctx context.Context, args map[string]ArgValue, flags map[string]FlagValue) error {
+			contexts = append(contexts, ctx)
+			return nil
+		}).
+		SetPostRun(func(ctx context.Context, args map[string]ArgValue, flags map[string]FlagValue) error {
+			contexts = append(contexts, ctx)
+			return nil
+		}).
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {})
+
+	if err := registry.ParseE([]string{"status"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(contexts) != 2 || contexts[0] != contexts[1] || contexts[0].Done() == nil {
+		t.Errorf("unexpected contexts: %v", contexts)
+	}
+}
+
+// usage information and error messages must be written to the writers of the registry
+func TestOutputWriters(t *testing.T) {
+	var stdout, stderr strings.Builder
+
+	registry := NewCommandRegistry()
+	registry.SetExecutableName("reactor").SetVersion("1.0.0").SetOut(&stdout).SetErr(&stderr)
+	registry.
+		Register("create").
+		SetShortDescription("creates a component").
+		AddArgument("name", "name of the component", "").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {})
+
+	// usage information and version
+	if err := registry.ParseE([]string{"create", "--help"}); err != nil || !strings.Contains(stdout.String(), "reactor create <name> {flags}") {
+		t.Errorf("unexpected output: %v, %s", err, stdout.String())
+	}
+
+	stdout.Reset()
+	if err := registry.ParseE([]string{"--version"}); err != nil || stdout.String() != "\nVersion: 1.0.0\n" {
+		t.Errorf("unexpected output: %v, %q", err, stdout.String())
+	}
+
+	// completion script
+	stdout.Reset()
+	if err := registry.WriteCompletion(registry.stdout(), "bash"); err != nil || !strings.Contains(stdout.String(), "complete -o default") {
+		t.Errorf("unexpected output: %v", err)
+	}
+
+	if stderr.Len() != 0 {
+		t.Errorf("unexpected error output: %s", stderr.String())
+	}
+
+	// error messages are displayed on the standard error of the process by default
+	processStdout, processStderr, code := runTestProgramStreams(t, []string{"NO_ROOT=TRUE"}, "tests/valid-registry.go", "craete")
+
+	if code != ExitCodeUsage || processStdout != "" || processStderr != "Error: craete is not a valid command.\n\nDid you mean this?\n   create\n" {
+		t.Errorf("unexpected output: %d, %q, %q", code, processStdout, processStderr)
+	}
+
+	// usage information is displayed on the standard output of the process by default
+	processStdout, processStderr, code = runTestProgramStreams(t, nil, "tests/valid-registry.go", "create", "--help")
+
+	if code != ExitCodeSuccess || processStderr != "" || !strings.Contains(processStdout, "Usage:\n   reactor create <name> [version] [files]... {flags}") {
+		t.Errorf("unexpected output: %d, %q, %q", code, processStdout, processStderr)
+	}
+}
+
+// usage information must display aliases, choices, environment variables, repeatable flags,
+// validators, persistent flags of the parent commands and constraints of the flag groups
+func TestHelpOutput(t *testing.T) {
+	var stdout strings.Builder
+
+	registry := NewCommandRegistry()
+	registry.SetExecutableName("reactor").SetEnvPrefix("REACTOR").SetOut(&stdout)
+	registry.Register(nil).AddPersistentFlag("verbose,V", "display log information", Bool, nil)
+	registry.
+		Register("export").
+		AddAlias("exp", "out").
+		SetShortDescription("exports the components").
+		AddArgument("format", "output format", "json").
+		SetArgumentChoices("format", "json", "yaml").
+		SetArgumentValidators("format", NonEmpty()).
+		AddFlag("file,f", "output file", String, nil).
+		AddFlag("stdout", "write to the standard output", Bool, nil).
+		AddFlag("include,i", "included paths", StringSlice, []string{}).
+		AddFlag("type,t", "content type", String, "simple").
+		AddFlag("port,p", "port", Int, 8080).
+		SetFlagChoices("type", "simple", "class").
+		SetFlagEnv("file", "EXPORT_FILE").
+		SetFlagValidators("port", Range(1, 65535)).
+		MutuallyExclusive("file", "stdout").
+		OneRequired("file", "stdout").
+		SetAction(func(args map[string]ArgValue, flags map[string]FlagValue) {})
+
+	// lines of the usage information (without trailing whitespaces)
+	help := func(args ...string) string {
+		stdout.Reset()
+		if err := registry.ParseE(args); err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(stdout.String(), "\n")
+		for index, line := range lines {
+			lines[index] = strings.TrimRight(line, " ")
+		}
+
+		return strings.Join(lines, "\n")
+	}
+
+	// aliases of the sub-commands
+	if output := help("--help"); !strings.Contains(output, "\nCommands:\n   export, exp, out              exports the components\n") {
+		t.Errorf("unexpected usage: %s", output)
+	}
+
+	expected := []string{
+		"\nArguments:\n   format                        output format (choices: json, yaml) (non-empty) (default: json)\n",
+		"\n   -f, --file                    output file [$EXPORT_FILE, $REACTOR_EXPORT_FILE]\n",
+		"\n   -i, --include                 included paths [$REACTOR_EXPORT_INCLUDE] (repeatable)\n",
+		"\n   -t, --type                    content type [$REACTOR_EXPORT_TYPE] (choices: simple, class) (default: simple)\n",
+		"\n   -p, --port                    port [$REACTOR_EXPORT_PORT] (between 1 and 65535) (default: 8080)\n",
+		"\nGlobal Flags:\n   -V, --verbose                 display log information [$REACTOR_VERBOSE] (default: false)\n",
+		"\nConstraints:\n   only one of --file, --stdout can be provided\n   one of --file, --stdout must be provided\n",
+	}
+
+	// usage of the sub-command (also displayed for an alias)
+	for _, args := range [][]string{{"export", "--help"}, {"out", "--help"}, {"help", "export"}} {
+		output := help(args...)
+		for _, value := range expected {
+			if !strings.Contains(output, value) {
+				t.Errorf("%v: %q is not displayed in %s", args, value, output)
+			}
 		}
 	}
 }
@@ -0,0 +1,438 @@
+package commando
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// automatic completion command description
+var (
+	completionCommandName      = "completion"
+	completionCommandDesc      = "This command outputs the shell completion script of this CLI application for bash, zsh, fish or powershell."
+	completionCommandShortDesc = "outputs shell completion script"
+	completionArgName          = "shell"
+	completionArgDesc          = "name of the shell"
+	completeCommandName        = "__complete"
+)
+
+// shell completion script templates
+var completionTemplates = map[string]string{
+	"bash":       bashCompletionTemplate,
+	"zsh":        zshCompletionTemplate,
+	"fish":       fishCompletionTemplate,
+	"powershell": powershellCompletionTemplate,
+}
+
+// supported shells (ordered list)
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+/*---------------------*/
+
+// Completion is a completion candidate of an argument or a flag value.
+type Completion struct {
+
+	// value to complete
+	Value string
+
+	// description of the value (displayed by zsh, fish and powershell)
+	Desc string
+}
+
+// CompletionContext holds the partial command-line arguments of the value being completed.
+type CompletionContext struct {
+
+	// command of the argument or the flag
+	Command *Command
+
+	// argument values provided before the value being completed
+	Args []string
+
+	// flag values provided before the value being completed (by long-name of the flag)
+	Flags map[string]string
+
+	// partial value being completed
+	ToComplete string
+}
+
+// CompletionFunc returns the completion candidates of an argument or a flag value.
+// The candidates are filtered with the partial value by the shell.
+type CompletionFunc func(ctx CompletionContext) []Completion
+
+/*---------------------*/
+
+// completionItem is a command or a flag name with its description
+type completionItem struct {
+	Name string
+	Desc string
+}
+
+// completionCommand holds the completion data of a command
+type completionCommand struct {
+
+	// names of the parent commands and the command separated by `/` (empty for the root-command)
+	Path string
+
+	// `<parent path>/<name or alias>` values that resolve to the command
+	Patterns []string
+
+	// sub-commands of the command
+	Commands []completionItem
+
+	// long and short flag names of the command (including persistent flags of the parent commands)
+	Flags []completionItem
+
+	// long and short flag names of the flags which take a value
+	ValueFlags []string
+}
+
+// get completion data of a command and its sub-commands
+func (cr *CommandRegistry) completionCommands(c *Command, parentPath string) []completionCommand {
+
+	// command data
+	data := completionCommand{
+		Commands:   make([]completionItem, 0),
+		Flags:      make([]completionItem, 0),
+		ValueFlags: make([]string, 0),
+	}
+
+	if !c.IsRoot {
+		data.Path = parentPath + "/" + c.Name()
+
+		for _, name := range append([]string{c.Name()}, c.Aliases...) {
+			data.Patterns = append(data.Patterns, parentPath+"/"+name)
+		}
+	}
+
+	// sub-commands
+	subCommands := cr.sortCommands(cr.subCommands(c))
+	for _, command := range subCommands {
+		data.Commands = append(data.Commands, completionItem{command.Name(), command.ShortDesc})
+	}
+
+	// flags and inherited persistent flags
+	c.registerInheritedFlags()
+	for _, flag := range append(cr.sortFlags(c.Flags), cr.sortFlags(c.inheritedFlags())...) {
+		clpFlag := c.clpCommandConfig.Flags[flag.ClpFlag.Name]
+
+		// long name (an inverted flag is used with the `--no-` prefix)
+		name := "--" + clpFlag.Name
+		if clpFlag.IsInverted {
+			name = "--no-" + clpFlag.Name
+		}
+
+		names := []string{name}
+		if clpFlag.ShortName != "" {
+			names = append(names, "-"+clpFlag.ShortName)
+		}
+
+		for _, name := range names {
+			data.Flags = append(data.Flags, completionItem{name, flag.Desc})
+
+			if !clpFlag.IsBoolean {
+				data.ValueFlags = append(data.ValueFlags, name)
+			}
+		}
+	}
+
+	// add sub-commands recursively
+	commands := []completionCommand{data}
+	for _, command := range subCommands {
+		commands = append(commands, cr.completionCommands(command, data.Path)...)
+	}
+
+	return commands
+}
+
+// WriteCompletion writes the shell completion script of the CLI application to `w`.
+// The supported shells are `bash`, `zsh`, `fish` and `powershell`.
+// The script completes commands (and their aliases), long and short flag names and inverted flags.
+func (cr *CommandRegistry) WriteCompletion(w io.Writer, shell string) error {
+
+	// get template of the shell
+	completionTemplate, ok := completionTemplates[shell]
+	if !ok {
+		return newParseError(ErrInvalidArgumentValue, completionArgName, nil, "value of the %s argument must be one of %s", completionArgName, strings.Join(completionShells, ", "))
+	}
+
+	// template data
+	templateData := struct {
+		Executable      string
+		FuncName        string
+		CompleteCommand string
+		Commands        []completionCommand
+	}{
+		Executable:      cr.Executable,
+		CompleteCommand: completeCommandName,
+		FuncName:        regexp.MustCompile(`[^A-Za-z0-9_]`).ReplaceAllString(cr.Executable, "_"),
+		Commands:        cr.completionCommands(cr.Commands[rootCommandName], ""),
+	}
+
+	// template functions to quote a string value for the shell
+	funcs := template.FuncMap{
+		"quote": func(value string) string {
+			switch shell {
+			case "fish":
+				return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+			case "powershell":
+				return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+			default:
+				return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+			}
+		},
+		"join": func(items []completionItem) string {
+			names := make([]string, 0)
+			for _, item := range items {
+				names = append(names, item.Name)
+			}
+
+			return strings.Join(names, " ")
+		},
+		"describe": func(item completionItem) string {
+			return strings.ReplaceAll(item.Name, ":", `\:`) + ":" + item.Desc
+		},
+		"joinNames": func(names []string) string {
+			return strings.Join(names, " ")
+		},
+		"contains": func(names []string, name string) bool {
+			for _, _name := range names {
+				if _name == name {
+					return true
+				}
+			}
+
+			return false
+		},
+		"hasPrefix":  strings.HasPrefix,
+		"trimPrefix": strings.TrimPrefix,
+	}
+
+	// parse completion template
+	tmpl, err := template.New(shell).Funcs(funcs).Parse(completionTemplate)
+	if err != nil {
+		panic(err)
+	}
+
+	// compile and output template result
+	return tmpl.Execute(w, templateData)
+}
+
+// EnableCompletion registers the `completion` command which outputs the shell completion script
+// of the CLI application, for example `$ reactor completion bash`. See `WriteCompletion`.
+// It also enables the hidden `__complete` command used by the scripts to get the candidates
+// of the `CompletionFunc` functions registered with the arguments and flags.
+func (cr *CommandRegistry) EnableCompletion() *CommandRegistry {
+	cr.completion = true
+
+	cr.
+		Register(completionCommandName).
+		SetDescription(completionCommandDesc).
+		SetShortDescription(completionCommandShortDesc).
+		AddArgument(completionArgName, completionArgDesc, "").
+		SetArgumentChoices(completionArgName, completionShells...).
+		SetActionE(func(ctx context.Context, args map[string]ArgValue, flags map[string]FlagValue) error {
+			return cr.WriteCompletion(cr.stdout(), args[completionArgName].Value)
+		})
+
+	// the `help` command completes the names of the sub-commands
+	cr.Commands[helpCommandName].SetArgumentCompletion(helpCommandArgName, func(ctx CompletionContext) []Completion {
+		completions := make([]Completion, 0)
+
+		if command, err := cr.lookupCommand(ctx.Args); err == nil {
+			for _, subCommand := range cr.sortCommands(cr.subCommands(command)) {
+				completions = append(completions, Completion{subCommand.Name(), subCommand.ShortDesc})
+			}
+		}
+
+		return completions
+	})
+
+	return cr
+}
+
+// get the completion candidates of the partial command-line arguments.
+// The last value of `words` is the partial value being completed.
+func (cr *CommandRegistry) complete(words []string) []Completion {
+	ctx := CompletionContext{
+		Command: cr.Commands[rootCommandName],
+		Args:    make([]string, 0),
+		Flags:   make(map[string]string),
+	}
+
+	if len(words) == 0 {
+		words = []string{""}
+	}
+
+	// flag waiting for its value
+	var valueFlag *Flag
+
+	// walk through the values provided before the value being completed
+	for _, word := range words[:len(words)-1] {
+
+		// value of a flag
+		if valueFlag != nil {
+			ctx.Flags[valueFlag.ClpFlag.Name] = word
+			valueFlag = nil
+			continue
+		}
+
+		// flag with or without a value (`--name=value`)
+		if isFlag(word) {
+			name, value, hasValue := word, "", false
+			if index := strings.Index(word, "="); index != -1 {
+				name, value, hasValue = word[:index], word[index+1:], true
+			}
+
+			flag := ctx.Command.findFlag(name)
+			switch {
+			case flag == nil:
+			case hasValue:
+				ctx.Flags[flag.ClpFlag.Name] = value
+			case flag.ClpFlag.IsBoolean:
+				ctx.Flags[flag.ClpFlag.Name] = fmt.Sprintf("%v", !flag.ClpFlag.IsInverted)
+			default:
+				valueFlag = flag
+			}
+
+			continue
+		}
+
+		// sub-command (sub-commands are followed by the arguments)
+		if len(ctx.Args) == 0 {
+			if command, ok := cr.findSubCommand(ctx.Command, word); ok {
+				ctx.Command = command
+				continue
+			}
+		}
+
+		ctx.Args = append(ctx.Args, word)
+	}
+
+	ctx.ToComplete = words[len(words)-1]
+
+	// value of a flag
+	if valueFlag != nil {
+		return choiceCompletions(ctx, valueFlag.Complete, valueFlag.Choices)
+	}
+
+	// flag names are completed by the shell
+	if isFlag(ctx.ToComplete) {
+		return nil
+	}
+
+	// value of an argument (the last variadic argument takes all the remaining values)
+	argNames := ctx.Command.clpCommandConfig.ArgNames
+	if len(argNames) == 0 {
+		return nil
+	}
+
+	name := argNames[len(argNames)-1]
+	if len(ctx.Args) < len(argNames) {
+		name = argNames[len(ctx.Args)]
+	} else if !ctx.Command.Args[name].ClpArg.IsVariadic {
+		return nil
+	}
+
+	arg := ctx.Command.Args[name]
+
+	return choiceCompletions(ctx, arg.Complete, arg.Choices)
+}
+
+// get the completion candidates of a value from the completion function or the choices
+func choiceCompletions(ctx CompletionContext, complete CompletionFunc, choices []string) []Completion {
+	if complete != nil {
+		return complete(ctx)
+	}
+
+	completions := make([]Completion, 0)
+	for _, choice := range choices {
+		completions = append(completions, Completion{Value: choice})
+	}
+
+	return completions
+}
+
+// write the completion candidates of the partial command-line arguments to `w` (one per line with
+// a tab-separated description). The first value of `args` is the number of values before the value
+// being completed, since some shells (e.g. powershell) drop an empty command-line argument.
+func (cr *CommandRegistry) writeCompletions(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	count, err := strconv.Atoi(args[0])
+	if err != nil || count < 0 || count > len(args)-1 {
+		return newParseError(ErrInvalidArgumentValue, completeCommandName, err, "%s is not a valid number of values", args[0])
+	}
+
+	// values before the value being completed and the partial value
+	words := append([]string{}, args[1:count+1]...)
+	if len(args) > count+1 {
+		words = append(words, args[count+1])
+	} else {
+		words = append(words, "")
+	}
+
+	for _, completion := range cr.complete(words) {
+		if completion.Desc != "" {
+			fmt.Fprintf(w, "%s\t%s\n", completion.Value, completion.Desc)
+		} else {
+			fmt.Fprintf(w, "%s\n", completion.Value)
+		}
+	}
+
+	return nil
+}
+
+/*---------------------*/
+
+// SetArgumentCompletion registers a function to get the completion candidates of an argument value at runtime.
+// The candidates are displayed by the shell completion script (see `CommandRegistry.EnableCompletion`).
+func (c *Command) SetArgumentCompletion(name string, complete CompletionFunc) *Command {
+	arg, ok := c.Args[strings.TrimSuffix(removeWhitespaces(name), "...")]
+	if !ok {
+		c.commandRegistry.fail("%s argument is not registered", name)
+	}
+
+	arg.Complete = complete
+
+	return c
+}
+
+// SetFlagCompletion registers a function to get the completion candidates of a flag value at runtime.
+// The name argument is the long-name of the flag. A flag without a completion function or choices is completed with file names.
+func (c *Command) SetFlagCompletion(name string, complete CompletionFunc) *Command {
+	flag, ok := c.Flags[strings.TrimPrefix(removeWhitespaces(name), "no-")]
+	if !ok {
+		c.commandRegistry.fail("--%s flag is not registered", name)
+	}
+
+	flag.Complete = complete
+
+	return c
+}
+
+// find a flag of the command (including inherited persistent flags) by its long or short name with dashes
+func (c *Command) findFlag(name string) *Flag {
+	flags := c.inheritedFlags()
+	for flagName, flag := range c.Flags {
+		flags[flagName] = flag
+	}
+
+	for flagName, clpFlag := range c.clpCommandConfig.Flags {
+		longName := clpFlag.Name
+		if clpFlag.IsInverted {
+			longName = "no-" + longName
+		}
+
+		if name == "--"+longName || (clpFlag.ShortName != "" && name == "-"+clpFlag.ShortName) {
+			return flags[flagName]
+		}
+	}
+
+	return nil
+}
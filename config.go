@@ -0,0 +1,600 @@
+package commando
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/thatisuday/clapper"
+)
+
+// config is the content of a configuration file. The values of the flags of the root-command are stored
+// at the top-level and the values of the flags of a sub-command are stored in a nested config with its name.
+// A value is a `string`, a `[]string` (repeated values) or a `config` (key=value pairs or a sub-command).
+type config map[string]interface{}
+
+// configParsers are the parsers of the configuration file formats (by file extension)
+var configParsers = map[string]func([]byte) (config, error){
+	".json": parseJSONConfig,
+	".ini":  parseINIConfig,
+	".toml": parseTOMLConfig,
+}
+
+// configBooleans are the boolean values of the INI files (e.g. `verbose = yes`) accepted by the `Bool` flags
+var configBooleans = map[string]string{
+	"yes": "true",
+	"on":  "true",
+	"no":  "false",
+	"off": "false",
+}
+
+/*---------------------*/
+
+// SetConfigFile sets the path of the default configuration file (e.g. `~/.reactor.json`) to get the values
+// of the flags from. The format of the file is detected from the extension (`.json`, `.ini` or `.toml`).
+// The values of the flags of a sub-command are stored in a section with the name of the sub-command (e.g. `create`
+// or `cluster.node`). The command-line and the environment variables take precedence over the configuration file.
+// If the default configuration file does not exist, it is ignored.
+func (cr *CommandRegistry) SetConfigFile(path string) *CommandRegistry {
+	cr.ConfigFile = path
+
+	return cr
+}
+
+// SetConfigFlag registers a persistent flag of the root-command (e.g. `config,c`) which overrides the path
+// of the configuration file set with `SetConfigFile`. Unlike the default configuration file, the configuration file
+// provided with the flag (or its environment variables) must exist.
+func (cr *CommandRegistry) SetConfigFlag(flagNames string, desc string) *CommandRegistry {
+	root := cr.Register(nil)
+
+	var defaultValue interface{}
+	if cr.ConfigFile != "" {
+		defaultValue = cr.ConfigFile
+	}
+
+	flag := root.addFlag(flagNames, desc, String, defaultValue)
+	flag.IsRequired = false
+
+	root.PersistentFlags[flag.ClpFlag.Name] = flag
+	cr.configFlag = flag.ClpFlag.Name
+
+	return cr
+}
+
+// read the configuration file from the path provided with the config flag or the default path
+func (cr *CommandRegistry) readConfig(flags map[string]*Flag, result *clapper.CommandConfig) (config, error) {
+	path, isDefault := cr.ConfigFile, true
+
+	if flag, ok := flags[cr.configFlag]; ok {
+		if value := result.Flags[cr.configFlag].Value; value != "" {
+			path, isDefault = value, false
+		} else if _, value, ok := flag.lookupEnv(); ok {
+			path, isDefault = value, false
+		}
+	}
+
+	if path == "" {
+		return nil, nil
+	}
+
+	cfg, err := loadConfig(expandHome(path))
+	if err != nil {
+		if isDefault && os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, newParseError(ErrInvalidConfig, cr.configFlag, err, "%v", err)
+	}
+
+	return cfg, nil
+}
+
+// load a configuration file (the format is detected from the file extension)
+func loadConfig(path string) (config, error) {
+	parse, ok := configParsers[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, fmt.Errorf("format of the %s config file is not supported (use .json, .ini or .toml)", path)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s config file is invalid: %v", path, err)
+	}
+
+	return cfg, nil
+}
+
+// expand `~` in a path to the home directory of the user
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, path[1:])
+}
+
+// get the values of a flag from the section of the command which registered the flag.
+// It returns the key of the value (e.g. `create.dir`) and the values as command-line argument values.
+// The value of a `Bool` flag can also be `yes`, `no`, `on` or `off`.
+func (cfg config) lookup(flag *Flag) (string, []string, bool) {
+	section := cfg
+
+	// built-in flags and the config flag are not read from the configuration file
+	if flag.command == nil || flag.ClpFlag.Name == helpFlagName || flag.ClpFlag.Name == versionFlagName ||
+		flag.ClpFlag.Name == flag.command.commandRegistry.configFlag {
+		return "", nil, false
+	}
+
+	keys := strings.Fields(flag.command.Path())
+
+	for _, key := range keys {
+		if section, _ = section[key].(config); section == nil {
+			return "", nil, false
+		}
+	}
+
+	key := strings.Join(append(keys, flag.ClpFlag.Name), ".")
+
+	switch value := section[flag.ClpFlag.Name].(type) {
+	case string:
+		if boolean, ok := configBooleans[strings.ToLower(value)]; ok && flag.DataType == Bool {
+			value = boolean
+		}
+
+		return key, []string{value}, true
+	case []string:
+		return key, value, true
+	case config:
+		// key=value pairs (values of a `StringMap` flag)
+		pairs := make([]string, 0, len(value))
+		for pairKey, pairValue := range value {
+			if pairValue, ok := pairValue.(string); ok {
+				pairs = append(pairs, pairKey+"="+pairValue)
+			}
+		}
+
+		sort.Strings(pairs)
+
+		return key, pairs, true
+	}
+
+	return "", nil, false
+}
+
+/*---------------------*/
+
+// convert a decoded JSON value to a config value
+func jsonConfigValue(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			_item, err := jsonConfigValue(item)
+			if err != nil {
+				return nil, err
+			}
+
+			if _item, ok := _item.(string); ok {
+				values = append(values, _item)
+			} else {
+				return nil, fmt.Errorf("an array can only contain strings, numbers and booleans")
+			}
+		}
+
+		return values, nil
+	case map[string]interface{}:
+		cfg := make(config)
+		for key, item := range value {
+			_item, err := jsonConfigValue(item)
+			if err != nil {
+				return nil, err
+			}
+
+			cfg[key] = _item
+		}
+
+		return cfg, nil
+	}
+
+	return nil, fmt.Errorf("null values are not supported")
+}
+
+// parse a JSON configuration file, for example `{"verbose": true, "create": {"dir": "./out"}}`
+func parseJSONConfig(data []byte) (config, error) {
+	var value map[string]interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	cfg, err := jsonConfigValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.(config), nil
+}
+
+/*---------------------*/
+
+// get the nested config of a section (e.g. `cluster.node`), creating it if necessary
+func (cfg config) section(keys []string) (config, error) {
+	section := cfg
+
+	for _, key := range keys {
+		if _, ok := section[key]; !ok {
+			section[key] = make(config)
+		}
+
+		next, ok := section[key].(config)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a section", key)
+		}
+
+		section = next
+	}
+
+	return section, nil
+}
+
+// parse an INI configuration file. The keys before the first section are the values of the root-command
+// and the name of a section is the path of a sub-command (e.g. `[cluster node]` or `[cluster.node]`).
+// A repeated key has multiple values.
+func parseINIConfig(data []byte) (config, error) {
+	cfg := make(config)
+	section := cfg
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		// empty line or comment
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// section
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			keys := strings.Fields(strings.Replace(line[1:len(line)-1], ".", " ", -1))
+
+			var err error
+			if section, err = cfg.section(keys); err != nil {
+				return nil, fmt.Errorf("line %d: %v", number, err)
+			}
+
+			continue
+		}
+
+		// key = value (or key: value)
+		index := strings.IndexAny(line, "=:")
+		if index < 1 {
+			return nil, fmt.Errorf("line %d: expected a key=value pair", number)
+		}
+
+		key := strings.TrimSpace(line[:index])
+		value := strings.TrimSpace(line[index+1:])
+
+		// remove quotes
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+
+		switch existing := section[key].(type) {
+		case nil:
+			section[key] = value
+		case string:
+			section[key] = []string{existing, value}
+		case []string:
+			section[key] = append(existing, value)
+		default:
+			return nil, fmt.Errorf("line %d: %s is a section", number, key)
+		}
+	}
+
+	return cfg, scanner.Err()
+}
+
+/*---------------------*/
+
+// tomlParser parses a subset of TOML: tables, dotted keys, strings, numbers, booleans, arrays and inline tables
+type tomlParser struct {
+	input    string
+	position int
+}
+
+// skip whitespaces, newlines (only if `newlines` is true) and comments
+func (p *tomlParser) skip(newlines bool) {
+	for p.position < len(p.input) {
+		switch p.input[p.position] {
+		case ' ', '\t', '\r':
+			p.position++
+		case '\n':
+			if !newlines {
+				return
+			}
+			p.position++
+		case '#':
+			for p.position < len(p.input) && p.input[p.position] != '\n' {
+				p.position++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// create an error with the line number of the current position
+func (p *tomlParser) errorf(format string, a ...interface{}) error {
+	line := strings.Count(p.input[:p.position], "\n") + 1
+
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, a...))
+}
+
+// parse a bare or quoted key
+func (p *tomlParser) key() (string, error) {
+	if p.position < len(p.input) && (p.input[p.position] == '"' || p.input[p.position] == '\'') {
+		return p.string()
+	}
+
+	start := p.position
+	for p.position < len(p.input) {
+		c := p.input[p.position]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			break
+		}
+		p.position++
+	}
+
+	if start == p.position {
+		return "", p.errorf("expected a key")
+	}
+
+	return p.input[start:p.position], nil
+}
+
+// parse a dotted key (e.g. `cluster.node`)
+func (p *tomlParser) keys() ([]string, error) {
+	keys := make([]string, 0)
+
+	for {
+		p.skip(false)
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+
+		p.skip(false)
+		if p.position >= len(p.input) || p.input[p.position] != '.' {
+			return keys, nil
+		}
+		p.position++
+	}
+}
+
+// parse a basic string ("...") or a literal string ('...')
+func (p *tomlParser) string() (string, error) {
+	quote := p.input[p.position]
+	start := p.position
+	p.position++
+
+	for p.position < len(p.input) && p.input[p.position] != quote && p.input[p.position] != '\n' {
+		if quote == '"' && p.input[p.position] == '\\' {
+			p.position++
+		}
+		p.position++
+	}
+
+	if p.position >= len(p.input) || p.input[p.position] != quote {
+		return "", p.errorf("unterminated string")
+	}
+	p.position++
+
+	if quote == '\'' {
+		return p.input[start+1 : p.position-1], nil
+	}
+
+	value, err := strconv.Unquote(p.input[start:p.position])
+	if err != nil {
+		return "", p.errorf("invalid string %s", p.input[start:p.position])
+	}
+
+	return value, nil
+}
+
+// parse a value
+func (p *tomlParser) value() (interface{}, error) {
+	if p.position >= len(p.input) {
+		return nil, p.errorf("expected a value")
+	}
+
+	switch p.input[p.position] {
+
+	// string
+	case '"', '\'':
+		if strings.HasPrefix(p.input[p.position:], `"""`) || strings.HasPrefix(p.input[p.position:], "'''") {
+			return nil, p.errorf("multi-line strings are not supported")
+		}
+
+		return p.string()
+
+	// array (of strings, numbers and booleans)
+	case '[':
+		p.position++
+		values := make([]string, 0)
+
+		for {
+			p.skip(true)
+			if p.position < len(p.input) && p.input[p.position] == ']' {
+				p.position++
+				return values, nil
+			}
+
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+
+			item, ok := value.(string)
+			if !ok {
+				return nil, p.errorf("an array can only contain strings, numbers and booleans")
+			}
+			values = append(values, item)
+
+			p.skip(true)
+			if p.position < len(p.input) && p.input[p.position] == ',' {
+				p.position++
+			} else if p.position >= len(p.input) || p.input[p.position] != ']' {
+				return nil, p.errorf("expected , or ] in an array")
+			}
+		}
+
+	// inline table
+	case '{':
+		p.position++
+		table := make(config)
+
+		for {
+			p.skip(false)
+			if p.position < len(p.input) && p.input[p.position] == '}' {
+				p.position++
+				return table, nil
+			}
+
+			if err := p.keyValue(table); err != nil {
+				return nil, err
+			}
+
+			p.skip(false)
+			if p.position < len(p.input) && p.input[p.position] == ',' {
+				p.position++
+			} else if p.position >= len(p.input) || p.input[p.position] != '}' {
+				return nil, p.errorf("expected , or } in an inline table")
+			}
+		}
+	}
+
+	// boolean or number
+	start := p.position
+	for p.position < len(p.input) && !strings.ContainsRune(" \t\r\n#,]}", rune(p.input[p.position])) {
+		p.position++
+	}
+
+	value := p.input[start:p.position]
+	if value == "true" || value == "false" {
+		return value, nil
+	}
+
+	number := strings.Replace(value, "_", "", -1)
+	if _, err := strconv.ParseFloat(number, 64); err == nil {
+		return number, nil
+	}
+	if _, err := strconv.ParseInt(number, 0, 64); err == nil {
+		return number, nil
+	}
+
+	return nil, p.errorf("unsupported value %s", value)
+}
+
+// parse a `key = value` pair and store it in the table
+func (p *tomlParser) keyValue(table config) error {
+	keys, err := p.keys()
+	if err != nil {
+		return err
+	}
+
+	if p.position >= len(p.input) || p.input[p.position] != '=' {
+		return p.errorf("expected = after %s", strings.Join(keys, "."))
+	}
+	p.position++
+	p.skip(false)
+
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+
+	section, err := table.section(keys[:len(keys)-1])
+	if err != nil {
+		return p.errorf("%v", err)
+	}
+
+	key := keys[len(keys)-1]
+	if _, ok := section[key]; ok {
+		return p.errorf("%s is defined more than once", strings.Join(keys, "."))
+	}
+
+	section[key] = value
+
+	return nil
+}
+
+// parse a TOML configuration file. The name of a table is the path of a sub-command (e.g. `[cluster.node]`).
+func parseTOMLConfig(data []byte) (config, error) {
+	cfg := make(config)
+	section := cfg
+
+	p := &tomlParser{input: string(data)}
+	for {
+		p.skip(true)
+		if p.position >= len(p.input) {
+			return cfg, nil
+		}
+
+		// table
+		if p.input[p.position] == '[' {
+			if strings.HasPrefix(p.input[p.position:], "[[") {
+				return nil, p.errorf("arrays of tables are not supported")
+			}
+			p.position++
+
+			keys, err := p.keys()
+			if err != nil {
+				return nil, err
+			}
+
+			if p.position >= len(p.input) || p.input[p.position] != ']' {
+				return nil, p.errorf("expected ] after the table name")
+			}
+			p.position++
+
+			if section, err = cfg.section(keys); err != nil {
+				return nil, p.errorf("%v", err)
+			}
+		} else if err := p.keyValue(section); err != nil {
+			return nil, err
+		}
+
+		// only a comment can follow on the same line
+		p.skip(false)
+		if p.position < len(p.input) && p.input[p.position] != '\n' {
+			return nil, p.errorf("expected a new line")
+		}
+	}
+}
@@ -21,8 +21,8 @@ func main() {
 	// $ reactor <category>  --verbose|-V  --version|-v  --help|-h
 	commando.
 		Register(nil).
-		AddArgument("category", "category of the information to look for", ""). // required
-		AddFlag("verbose,V", "display log information ", commando.Bool, nil).   // optional
+		AddArgument("category", "category of the information to look for", "").         // required
+		AddPersistentFlag("verbose,V", "display log information ", commando.Bool, nil). // optional, accepted by all commands
 		SetAction(func(args map[string]commando.ArgValue, flags map[string]commando.FlagValue) {
 			// print arguments
 			for k, v := range args {
@@ -36,19 +36,18 @@ func main() {
 		})
 
 	// register `create` sub-command
-	// $ reactor create <name> [version] [files] --dir|-d <dir>  --type|-t [type]  --timeout [timeout]  --verbose|-v  --help|-h  --no-clean
+	// $ reactor create <name> [version] [files]... --dir|-d <dir>  --type|-t [type]  --timeout [timeout]  --verbose|-V  --help|-h  --no-clean
 	commando.
 		Register("create").
 		SetDescription("This command creates a component of a given type and outputs component files in the project directory.").
 		SetShortDescription("creates a component").
-		AddArgument("name", "name of the component to create", "").                                  // required
-		AddArgument("version", "version of the component", "1.0.0").                                 // optional
-		AddArgument("files...", "files to remove once component is created", "").                    // variadic, optional
-		AddFlag("dir, d", "output directory for the component files", commando.String, nil).         // required
-		AddFlag("type, t", "type of the component to create", commando.String, "simple_type").       // optional
-		AddFlag("timeout", "operation timeout in seconds", commando.Int, 60).                        // optional
-		AddFlag("verbose,v", "display logs while creating the component files", commando.Bool, nil). // optional
-		AddFlag("no-clean", "avoid cleanup of the component directory", commando.Bool, nil).         // optional, inverted flag
+		AddArgument("name", "name of the component to create", "").                            // required
+		AddArgument("version", "version of the component", "1.0.0").                           // optional
+		AddArgument("files...", "files to remove once component is created", "").              // variadic, optional
+		AddFlag("dir, d", "output directory for the component files", commando.String, nil).   // required
+		AddFlag("type, t", "type of the component to create", commando.String, "simple_type"). // optional
+		AddFlag("timeout", "operation timeout in seconds", commando.Int, 60).                  // optional
+		AddFlag("no-clean", "avoid cleanup of the component directory", commando.Bool, nil).   // optional, inverted flag
 		SetAction(func(args map[string]commando.ArgValue, flags map[string]commando.FlagValue) {
 			// print arguments
 			for k, v := range args {
@@ -62,12 +61,11 @@ func main() {
 		})
 
 	// register `serve` sub-command
-	// $ reactor serve --verbose|-v  --help|-h
+	// $ reactor serve --verbose|-V  --help|-h
 	commando.
 		Register("serve").
 		SetDescription("This command starts the Webpack dev-server on an available port.").
 		SetShortDescription("starts a development server").
-		AddFlag("verbose,v", "display logs while serving the project", commando.Bool, nil). // optional
 		SetAction(func(args map[string]commando.ArgValue, flags map[string]commando.FlagValue) {
 			// print arguments
 			for k, v := range args {
@@ -81,13 +79,12 @@ func main() {
 		})
 
 	// register `build` sub-command
-	// $ reactor build  --dir|-d <dir>  --verbose|-v  --help|-h
+	// $ reactor build  --dir|-d <dir>  --verbose|-V  --help|-h
 	commando.
 		Register("build").
 		SetDescription("This command builds the project with Webpack and outputs the build files in the given directory.").
 		SetShortDescription("creates build artifacts").
-		AddFlag("dir,d", "output directory of the build files", commando.String, nil).      // required
-		AddFlag("verbose,v", "display logs while serving the project", commando.Bool, nil). // optional
+		AddFlag("dir,d", "output directory of the build files", commando.String, nil). // required
 		SetAction(func(args map[string]commando.ArgValue, flags map[string]commando.FlagValue) {
 			// print arguments
 			for k, v := range args {
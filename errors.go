@@ -0,0 +1,119 @@
+package commando
+
+import (
+	"errors"
+	"fmt"
+)
+
+// error kinds returned by `ParseE` (use `errors.Is` to check the kind of an error)
+var (
+	// ErrUnknownCommand indicates that the command is not registered.
+	ErrUnknownCommand = errors.New("unknown command")
+
+	// ErrUnknownFlag indicates that the flag is not registered with the command.
+	ErrUnknownFlag = errors.New("unknown flag")
+
+	// ErrUnsupportedFlag indicates that the flag has an unsupported format (e.g. `---flag`).
+	ErrUnsupportedFlag = errors.New("unsupported flag")
+
+	// ErrMissingArgument indicates that the value of a required argument is missing.
+	ErrMissingArgument = errors.New("missing argument")
+
+	// ErrMissingFlag indicates that the value of a required flag is missing.
+	ErrMissingFlag = errors.New("missing flag")
+
+	// ErrConflictingFlags indicates that mutually exclusive flags are used together.
+	ErrConflictingFlags = errors.New("conflicting flags")
+
+	// ErrInvalidFlagValue indicates that the value of a flag can not be converted to its data type.
+	ErrInvalidFlagValue = errors.New("invalid flag value")
+
+	// ErrInvalidArgumentValue indicates that the value of an argument is not valid.
+	ErrInvalidArgumentValue = errors.New("invalid argument value")
+
+	// ErrTooManyArguments indicates that a variadic argument has more values than its arity allows.
+	ErrTooManyArguments = errors.New("too many arguments")
+
+	// ErrRuleViolation indicates that a validation rule registered with `AddRule` is not satisfied.
+	ErrRuleViolation = errors.New("rule violation")
+
+	// ErrInvalidConfig indicates that the configuration file can not be read or parsed.
+	ErrInvalidConfig = errors.New("invalid config file")
+
+	// ErrMissingAction indicates that the action function of a sub-command is not registered.
+	ErrMissingAction = errors.New("missing action")
+)
+
+/*---------------------*/
+
+// ParseError represents an error returned by `ParseE` when the command-line arguments can not be processed.
+// It matches one of the `Err*` values with `errors.Is` and unwraps to the underlying `clapper` error if any.
+type ParseError struct {
+
+	// kind of the error (one of the `Err*` values)
+	Kind error
+
+	// name of the command, argument or flag that caused the error
+	Name string
+
+	// underlying error (e.g. `clapper.ErrorUnknownCommand`)
+	Err error
+
+	// similar command or flag names for an unknown command or flag
+	Suggestions []string
+
+	// human readable error message
+	message string
+}
+
+// Error returns the error message.
+func (e *ParseError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error is of the `target` kind.
+func (e *ParseError) Is(target error) bool {
+	return e.Kind == target
+}
+
+// create a new `ParseError` value
+func newParseError(kind error, name string, err error, format string, a ...interface{}) *ParseError {
+	return &ParseError{
+		Kind:    kind,
+		Name:    name,
+		Err:     err,
+		message: fmt.Sprintf(format, a...),
+	}
+}
+
+/*---------------------*/
+
+// ExitError can be returned by an action function to exit the process with a specific exit code.
+// If `Err` is `nil`, `Parse` exits the process without displaying an error message.
+type ExitError struct {
+
+	// exit code of the process
+	Code int
+
+	// underlying error
+	Err error
+}
+
+// Error returns the error message of the underlying error.
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
@@ -5,26 +5,25 @@ var usageTemplate = `
 
 Usage:
    {{ .Executable }} {{ if not .IsRootCommand }}{{ .Command }} {{ end }}{{ with .Args -}}
-   {{ range $k, $v := . }}{{ if $v.IsRequired }}<{{ $v.ClpArg.Name }}>{{ else }}[{{ $v.ClpArg.Name }}]{{ end }} {{ end }}{{ end }}{flags}{{- if .IsRootCommand }}{{ if .Commands }}
-   {{ .Executable }} <command> {flags}{{ end }}{{ end -}}
+   {{ range $k, $v := . }}{{ if $v.IsRequired }}<{{ $v.ClpArg.Name }}>{{ else }}[{{ $v.ClpArg.Name }}]{{ end }}{{ if $v.ClpArg.IsVariadic }}...{{ end }} {{ end }}{{ end }}{flags}{{- if .Commands }}
+   {{ .Executable }} {{ if not .IsRootCommand }}{{ .Command }} {{ end }}<command> {flags}{{ end -}}
 
 
 {{- /* commands */ -}}
-{{- if .IsRootCommand -}}
 {{- with .Commands  }}
 
-Commands: {{ range $k, $v := . }}
-   {{ printf "%-30v" $k }}{{ $v.ShortDesc }}
+Commands: {{ range $v := . }}
+   {{- $names := $v.Name }}{{ range $v.Aliases }}{{ $names = printf "%s, %s" $names . }}{{ end }}
+   {{ printf "%-30v" $names }}{{ $v.ShortDesc }}
    {{- end -}}
 {{- end -}}
-{{- end -}}
 
 
 {{- /* arguments */ -}}
 {{- with .Args }}
 
 Arguments: {{ range $k, $v := . }}
-   {{ printf "%-30v" $v.ClpArg.Name }}{{ $v.Desc }}{{ if $v.ClpArg.DefaultValue }} (default: {{ $v.ClpArg.DefaultValue }}){{ end }}{{ if $v.ClpArg.IsVariadic }} {variadic}{{ end }}
+   {{ printf "%-30v" $v.ClpArg.Name }}{{ $v.Desc }}{{ with $v.Choices }} (choices: {{ join . ", " }}){{ end }}{{ range $v.Validators }} ({{ . }}){{ end }}{{ if $v.ClpArg.DefaultValue }} (default: {{ $v.ClpArg.DefaultValue }}){{ end }}{{ if $v.ClpArg.IsVariadic }} {variadic{{ if or $v.Arity.Min (ge $v.Arity.Max 0) }}: {{ $v.Arity }}{{ end }}}{{ end }}
    {{- end -}}
 {{- end -}}
 
@@ -32,11 +31,22 @@ Arguments: {{ range $k, $v := . }}
 {{- /* flags */ -}}
 {{- with .Flags }}
 
-Flags: {{ range $k, $v := . }}
-   {{ if $v.ClpFlag.ShortName -}}-{{ $v.ClpFlag.ShortName }}, {{ if $v.ClpFlag.IsInverted }}{{ printf "--no-%-24v" $k }}{{ else }}{{ printf "--%-24v" $k }}{{ end -}}
-   {{ else }}{{ if $v.ClpFlag.IsInverted }}{{ printf "--no-%-25v" $k }}{{ else }}{{ printf "--%-28v" $k }}{{ end -}}
-   {{- end -}}
-   {{- $v.Desc }} {{ if $v.ClpFlag.DefaultValue }}(default: {{ if $v.ClpFlag.IsInverted }}false{{ else }}{{ $v.ClpFlag.DefaultValue }}{{ end }}){{ end }}
+Flags: {{ template "flags" . }}
+{{- end -}}
+
+
+{{- /* persistent flags of the parent commands */ -}}
+{{- with .GlobalFlags }}
+
+Global Flags: {{ template "flags" . }}
+{{- end -}}
+
+
+{{- /* constraints between the flags */ -}}
+{{- with .Constraints }}
+
+Constraints: {{ range $v := . }}
+   {{ $v }}
    {{- end -}}
 {{- end -}}
 
@@ -45,9 +55,276 @@ Flags: {{ range $k, $v := . }}
 {{- "" }}
 `
 
+var flagsTemplate = `
+{{- define "flags" -}}
+{{- range $v := . }}{{ $k := $v.ClpFlag.Name }}
+   {{ if $v.ClpFlag.ShortName -}}-{{ $v.ClpFlag.ShortName }}, {{ if $v.ClpFlag.IsInverted }}{{ printf "--no-%-24v" $k }}{{ else }}{{ printf "--%-24v" $k }}{{ end -}}
+   {{ else }}{{ if $v.ClpFlag.IsInverted }}{{ printf "--no-%-25v" $k }}{{ else }}{{ printf "--%-28v" $k }}{{ end -}}
+   {{- end -}}
+   {{- $v.Desc }} {{ with $v.EnvNames }}[${{ join . ", $" }}] {{ end }}{{ with $v.Choices }}(choices: {{ join . ", " }}) {{ end }}{{ range $v.Validators }}({{ . }}) {{ end }}{{ if $v.IsRepeatable }}(repeatable) {{ end }}{{ if $v.ClpFlag.DefaultValue }}(default: {{ if $v.ClpFlag.IsInverted }}false{{ else }}{{ $v.ClpFlag.DefaultValue }}{{ end }}){{ end }}
+{{- end -}}
+{{- end -}}
+`
+
 var versionTemplate = `
 Version: {{ .Version }}
 
 {{- /* end */ -}}
 {{- "" }}
 `
+
+var bashCompletionTemplate = `# bash completion for {{ .Executable }}
+# (add "source <({{ .Executable }} completion bash)" to ~/.bashrc)
+
+# set commands, flags and flags with a value of a command path
+__{{ .FuncName }}_command() {
+    case "$1" in
+{{- range .Commands }}
+        {{ quote .Path }})
+            commands={{ quote (join .Commands) }}
+            flags={{ quote (join .Flags) }}
+            values={{ quote (joinNames .ValueFlags) }}
+            ;;
+{{- end }}
+    esac
+}
+
+__{{ .FuncName }}_complete() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    local path="" word="" skip="" commands="" flags="" values="" i
+
+    # find the command path
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        word="${COMP_WORDS[i]}"
+        __{{ .FuncName }}_command "$path"
+
+        if [[ -n "$skip" ]]; then
+            skip=""
+        elif [[ "$word" == -* ]]; then
+            [[ " $values " == *" $word "* ]] && skip=1
+        else
+            case "$path/$word" in
+{{- range .Commands }}{{ if .Patterns }}
+                {{ range $i, $pattern := .Patterns }}{{ if $i }}|{{ end }}{{ quote $pattern }}{{ end }}) path={{ quote .Path }} ;;
+{{- end }}{{ end }}
+            esac
+        fi
+    done
+
+    __{{ .FuncName }}_command "$path"
+
+    # flag names
+    if [[ "$cur" == -* && !( -n "$prev" && " $values " == *" $prev "* ) ]]; then
+        COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+        return
+    fi
+
+    # candidates of a flag value or an argument (one per line with a description)
+    local IFS=$'\n' candidates
+    candidates=$({{ quote .Executable }} {{ .CompleteCommand }} "$((COMP_CWORD - 1))" "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null | cut -f 1)
+
+    # value of a flag (default completion without candidates)
+    if [[ -n "$prev" && " $values " == *" $prev "* ]]; then
+        COMPREPLY=($(compgen -W "$candidates" -- "$cur"))
+    else
+        COMPREPLY=($(compgen -W "${commands// /$'\n'}"$'\n'"$candidates" -- "$cur"))
+    fi
+}
+
+complete -o default -F __{{ .FuncName }}_complete {{ .Executable }}
+`
+
+var zshCompletionTemplate = `#compdef {{ .Executable }}
+# zsh completion for {{ .Executable }}
+# (add "source <({{ .Executable }} completion zsh)" to ~/.zshrc after "compinit")
+
+# set commands, flags and flags with a value of a command path
+__{{ .FuncName }}_command() {
+    case "$1" in
+{{- range .Commands }}
+        {{ quote .Path }})
+            commands=({{ range .Commands }} {{ quote (describe .) }}{{ end }} )
+            flags=({{ range .Flags }} {{ quote (describe .) }}{{ end }} )
+            values=({{ range .ValueFlags }} {{ quote . }}{{ end }} )
+            ;;
+{{- end }}
+    esac
+}
+
+__{{ .FuncName }}_complete() {
+    local path="" word="" skip=""
+    local -a commands flags values
+
+    # find the command path
+    for word in "${(@)words[2,CURRENT-1]}"; do
+        __{{ .FuncName }}_command "$path"
+
+        if [[ -n "$skip" ]]; then
+            skip=""
+        elif [[ "$word" == -* ]]; then
+            (( ${values[(Ie)$word]} )) && skip=1
+        else
+            case "$path/$word" in
+{{- range .Commands }}{{ if .Patterns }}
+                {{ range $i, $pattern := .Patterns }}{{ if $i }}|{{ end }}{{ quote $pattern }}{{ end }}) path={{ quote .Path }} ;;
+{{- end }}{{ end }}
+            esac
+        fi
+    done
+
+    __{{ .FuncName }}_command "$path"
+
+    # flag names
+    if [[ "$PREFIX" == -* ]] && ! (( ${values[(Ie)${words[CURRENT-1]}]} )); then
+        _describe -t flags 'flag' flags
+        return
+    fi
+
+    # candidates of a flag value or an argument (value<tab>description lines to value:description)
+    local -a candidates
+    candidates=(${(f)"$({{ quote .Executable }} {{ .CompleteCommand }} "$(( CURRENT - 2 ))" "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    candidates=("${(@)candidates//:/\\:}")
+    candidates=("${(@)candidates/$'\t'/:}")
+
+    # value of a flag (file completion without candidates)
+    if (( ${values[(Ie)${words[CURRENT-1]}]} )); then
+        if (( ${#candidates} )); then
+            _describe -t values 'value' candidates
+        else
+            _files
+        fi
+    else
+        _describe -t commands 'command' commands -- candidates
+    fi
+}
+
+compdef __{{ .FuncName }}_complete {{ .Executable }}
+`
+
+var fishCompletionTemplate = `# fish completion for {{ .Executable }}
+# (run "{{ .Executable }} completion fish > ~/.config/fish/completions/{{ .Executable }}.fish")
+
+# print flags with a value of a command path
+function __{{ .FuncName }}_values
+    switch "$argv[1]"
+{{- range .Commands }}
+        case {{ quote .Path }}
+            printf '%s\n'{{ range .ValueFlags }} {{ quote . }}{{ end }}
+{{- end }}
+    end
+end
+
+# print the command path
+function __{{ .FuncName }}_path
+    set -l path ''
+    set -l skip 0
+    set -l words (commandline -opc)
+
+    for word in $words[2..-1]
+        if test $skip -eq 1
+            set skip 0
+        else if string match -q -- '-*' $word
+            contains -- $word (__{{ .FuncName }}_values "$path"); and set skip 1
+        else
+            switch "$path/$word"
+{{- range .Commands }}{{ if .Patterns }}
+                case{{ range .Patterns }} {{ quote . }}{{ end }}
+                    set path {{ quote .Path }}
+{{- end }}{{ end }}
+            end
+        end
+    end
+
+    echo $path
+end
+
+# check the command path
+function __{{ .FuncName }}_at
+    set -l path (__{{ .FuncName }}_path)
+    test "$path" = "$argv[1]"
+end
+
+# print candidates of a flag value or an argument (one per line with a description)
+function __{{ .FuncName }}_candidates
+    set -l words (commandline -opc)
+    {{ quote .Executable }} {{ .CompleteCommand }} (math (count $words) - 1) $words[2..-1] (commandline -ct) 2>/dev/null
+end
+
+complete -c {{ .Executable }} -f
+complete -c {{ .Executable }} -n "not string match -q -- '-*' (commandline -ct)" -a "(__{{ .FuncName }}_candidates)"
+{{- range $command := .Commands }}
+{{- range .Commands }}
+complete -c {{ $.Executable }} -n "__{{ $.FuncName }}_at {{ quote $command.Path }}" -a {{ quote .Name }} -d {{ quote .Desc }}
+{{- end }}
+{{- range .Flags }}
+complete -c {{ $.Executable }} -n "__{{ $.FuncName }}_at {{ quote $command.Path }}" {{ if hasPrefix .Name "--" }}-l {{ quote (trimPrefix .Name "--") }}{{ else }}-s {{ quote (trimPrefix .Name "-") }}{{ end }} -d {{ quote .Desc }}{{ if contains $command.ValueFlags .Name }} -r -F -a "(__{{ $.FuncName }}_candidates)"{{ end }}
+{{- end }}
+{{- end }}
+`
+
+var powershellCompletionTemplate = `# powershell completion for {{ .Executable }}
+# (add "{{ .Executable }} completion powershell | Out-String | Invoke-Expression" to $PROFILE)
+
+Register-ArgumentCompleter -Native -CommandName {{ quote .Executable }} -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    # commands, flags and flags with a value of the command paths
+    $commands = @{
+{{- range .Commands }}
+        {{ quote .Path }} = @({{ range .Commands }} @({{ quote .Name }}, {{ quote .Desc }}),{{ end }} $null)
+{{- end }}
+    }
+    $flags = @{
+{{- range .Commands }}
+        {{ quote .Path }} = @({{ range .Flags }} @({{ quote .Name }}, {{ quote .Desc }}),{{ end }} $null)
+{{- end }}
+    }
+    $values = @{
+{{- range .Commands }}
+        {{ quote .Path }} = @({{ range .ValueFlags }} {{ quote . }},{{ end }} $null)
+{{- end }}
+    }
+
+    # find the command path (words before the cursor)
+    $path = ''
+    $skip = $false
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | Where-Object { $_.Extent.EndOffset -lt $cursorPosition } | ForEach-Object { $_.ToString() })
+
+    foreach ($word in $words) {
+        if ($skip) {
+            $skip = $false
+        } elseif ($word -like '-*') {
+            $skip = $values[$path] -contains $word
+        } else {
+            switch ("$path/$word") {
+{{- range .Commands }}{{ if .Patterns }}
+                { $_ -in @({{ range $i, $pattern := .Patterns }}{{ if $i }}, {{ end }}{{ quote $pattern }}{{ end }}) } { $path = {{ quote .Path }} }
+{{- end }}{{ end }}
+            }
+        }
+    }
+
+    # candidates of a flag value or an argument (value<tab>description lines)
+    $isValue = $words.Count -gt 0 -and $values[$path] -contains $words[-1]
+    $candidates = @()
+    if ($isValue -or $wordToComplete -notlike '-*') {
+        $candidates = @(& {{ quote .Executable }} {{ .CompleteCommand }} $words.Count @words $wordToComplete 2>$null | ForEach-Object { , @($_ -split "` + "`" + `t", 2) })
+    }
+
+    # value of a flag (default completion without candidates)
+    if ($isValue) {
+        if ($candidates.Count -eq 0) {
+            return
+        }
+    } elseif ($wordToComplete -like '-*') {
+        $candidates = $flags[$path]
+    } else {
+        $candidates = @($commands[$path]) + $candidates
+    }
+
+    $candidates | Where-Object { $_ -ne $null -and $_[0] -like "$wordToComplete*" } | ForEach-Object {
+        $toolTip = if ($_[1]) { $_[1] } else { $_[0] }
+        [System.Management.Automation.CompletionResult]::new($_[0], $_[0], 'ParameterValue', $toolTip)
+    }
+}
+`
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/thatisuday/commando"
+)
+
+func main() {
+	registry := commando.NewCommandRegistry()
+	registry.SetExecutableName("reactor")
+	registry.Register("create")
+	registry.Register("build").AddAlias("create")
+	registry.Parse(nil)
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/thatisuday/commando"
+)
+
+func main() {
+	registry := commando.NewCommandRegistry()
+	registry.SetExecutableName("reactor")
+	registry.Register("create").AddArgument("name", "name of the component", "")
+	registry.EnableCompletion()
+	registry.Parse(nil)
+}
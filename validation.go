@@ -0,0 +1,347 @@
+package commando
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RuleFunc is a validation rule of the argument and flag values of a command (see `AddRule`).
+// It returns an error if the values are not valid.
+type RuleFunc func(args map[string]ArgValue, flags map[string]FlagValue) error
+
+// kinds of the flag groups
+const (
+	mutuallyExclusiveGroup = iota
+	requiredTogetherGroup
+	oneRequiredGroup
+)
+
+// FlagGroup is a constraint between the flags of a command (see `MutuallyExclusive`, `RequiredTogether` and `OneRequired`).
+type FlagGroup struct {
+
+	// long-names of the flags
+	Flags []string
+
+	// kind of the constraint
+	kind int
+}
+
+// String returns the description of the constraint for the usage information.
+func (group FlagGroup) String() string {
+	names := "--" + strings.Join(group.Flags, ", --")
+
+	switch group.kind {
+	case mutuallyExclusiveGroup:
+		return fmt.Sprintf("only one of %s can be provided", names)
+	case requiredTogetherGroup:
+		return fmt.Sprintf("%s must be provided together", names)
+	}
+
+	return fmt.Sprintf("one of %s must be provided", names)
+}
+
+// check the constraint against the resolved flag values (a flag is provided if its value is set by the user)
+func (group FlagGroup) check(flagValues map[string]FlagValue) error {
+	provided, missing := make([]string, 0), make([]string, 0)
+	for _, name := range group.Flags {
+		if flagValues[name].IsSet() {
+			provided = append(provided, name)
+		} else {
+			missing = append(missing, name)
+		}
+	}
+
+	switch group.kind {
+	case mutuallyExclusiveGroup:
+		if len(provided) > 1 {
+			return newParseError(ErrConflictingFlags, provided[1], nil, "--%s and --%s flags can not be used together", provided[0], provided[1])
+		}
+	case requiredTogetherGroup:
+		if len(provided) > 0 && len(missing) > 0 {
+			return newParseError(ErrMissingFlag, missing[0], nil, "--%s flag is required with the --%s flag", missing[0], provided[0])
+		}
+	case oneRequiredGroup:
+		if len(provided) == 0 {
+			return newParseError(ErrMissingFlag, group.Flags[0], nil, "one of the --%s flags is required", strings.Join(group.Flags, ", --"))
+		}
+	}
+
+	return nil
+}
+
+// register a flag group with the command.
+// The flags of a mutually exclusive or one-required group are not required individually, the group decides
+// whether they must be provided (a flag registered as required is made optional).
+func (c *Command) addFlagGroup(kind int, names []string) *Command {
+	if len(names) < 2 {
+		c.commandRegistry.fail("a flag group of the %s command must have at least two flags", c.Path())
+	}
+
+	group := FlagGroup{kind: kind}
+	for _, name := range names {
+		name = strings.TrimPrefix(removeWhitespaces(name), "no-")
+
+		flag, ok := c.Flags[name]
+		if !ok {
+			c.commandRegistry.fail("--%s flag is not registered", name)
+		}
+
+		if kind != requiredTogetherGroup {
+			flag.IsRequired = false
+		}
+
+		group.Flags = append(group.Flags, name)
+	}
+
+	c.FlagGroups = append(c.FlagGroups, group)
+
+	return c
+}
+
+// MutuallyExclusive registers a group of flags which can not be used together, for example `MutuallyExclusive("file", "stdout")`.
+// The arguments are the long-names of the flags registered with the command.
+// A flag is provided if its value is set with the command-line, an environment variable or the configuration file.
+// The flags of the group are not required individually: a flag registered as required (without a default-value)
+// is made optional and gets the zero value of its data-type when it is not provided.
+func (c *Command) MutuallyExclusive(names ...string) *Command {
+	return c.addFlagGroup(mutuallyExclusiveGroup, names)
+}
+
+// RequiredTogether registers a group of flags which must be provided together if any of them is provided,
+// for example `RequiredTogether("width", "height")`. The arguments are the same as `MutuallyExclusive`.
+// A flag registered as required (without a default-value) remains required, so the flags of the group need default-values.
+func (c *Command) RequiredTogether(names ...string) *Command {
+	return c.addFlagGroup(requiredTogetherGroup, names)
+}
+
+// OneRequired registers a group of flags of which at least one must be provided, for example `OneRequired("file", "stdout")`.
+// Combined with `MutuallyExclusive`, exactly one of the flags must be provided.
+// The arguments and the required flags are handled like `MutuallyExclusive`.
+func (c *Command) OneRequired(names ...string) *Command {
+	return c.addFlagGroup(oneRequiredGroup, names)
+}
+
+/*---------------------*/
+
+// AddRule registers a validation rule which checks the argument and flag values after they are resolved
+// and before the action function is executed, for example a flag which is required only when another flag has a value.
+// The error returned by the rule is reported as an usage error (a `*ParseError` value of the `ErrRuleViolation` kind
+// unless the rule returns a `*ParseError` value). The rules are checked in the registration order.
+func (c *Command) AddRule(rule RuleFunc) *Command {
+	if rule == nil {
+		c.commandRegistry.fail("rule of the %s command can not be nil", c.Path())
+	}
+
+	c.Rules = append(c.Rules, rule)
+
+	return c
+}
+
+// check a validation rule and convert its error to an usage error
+func checkRule(rule RuleFunc, args map[string]ArgValue, flags map[string]FlagValue) error {
+	err := rule(args, flags)
+	if err == nil {
+		return nil
+	}
+
+	var parseError *ParseError
+	if errors.As(err, &parseError) {
+		return err
+	}
+
+	return newParseError(ErrRuleViolation, "", err, "%s", err.Error())
+}
+
+/*---------------------*/
+
+// Validator validates the value of a flag or an argument after it is converted to its data type
+// (see `SetFlagValidators` and `SetArgumentValidators`).
+type Validator interface {
+
+	// Validate returns an error if the value is not valid. The error message completes the sentence
+	// "value of the --port flag ...", for example `must be between 1 and 65535`.
+	Validate(value interface{}) error
+
+	// String returns the description of the constraint for the usage information (e.g. `between 1 and 65535`)
+	String() string
+}
+
+// validator is a `Validator` implementation with a description and a validation function
+type validator struct {
+	desc     string
+	validate func(value interface{}) error
+}
+
+// Validate calls the validation function.
+func (v validator) Validate(value interface{}) error {
+	return v.validate(value)
+}
+
+// String returns the description of the validator.
+func (v validator) String() string {
+	return v.desc
+}
+
+// NewValidator creates a `Validator` with a description and a validation function.
+func NewValidator(desc string, validate func(value interface{}) error) Validator {
+	return validator{desc, validate}
+}
+
+// validate every item of a slice or a map (values of a repeatable flag or a variadic argument) or the value itself
+func validateItems(value interface{}, validate func(item interface{}) error) error {
+	_value := reflect.ValueOf(value)
+
+	items := make([]interface{}, 0)
+	switch _value.Kind() {
+	case reflect.Slice:
+		for index := 0; index < _value.Len(); index++ {
+			items = append(items, _value.Index(index).Interface())
+		}
+	case reflect.Map:
+		keys := _value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+
+		for _, key := range keys {
+			items = append(items, _value.MapIndex(key).Interface())
+		}
+	default:
+		items = append(items, value)
+	}
+
+	for _, item := range items {
+		if err := validate(item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// run validators on a value and return the first error
+func validate(validators []Validator, value interface{}) error {
+	for _, validator := range validators {
+		if err := validator.Validate(value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Range validates that a number (or every number of a repeatable flag or a variadic argument)
+// is between `min` and `max` (inclusive). A `Duration` value is compared in nanoseconds.
+func Range(min float64, max float64) Validator {
+	desc := fmt.Sprintf("between %v and %v", min, max)
+
+	return NewValidator(desc, func(value interface{}) error {
+		return validateItems(value, func(item interface{}) error {
+			var number float64
+
+			_item := reflect.ValueOf(item)
+			switch _item.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				number = float64(_item.Int())
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				number = float64(_item.Uint())
+			case reflect.Float32, reflect.Float64:
+				number = _item.Float()
+			default:
+				return fmt.Errorf("must be a number %s", desc)
+			}
+
+			if number < min || number > max {
+				return fmt.Errorf("must be %s (got %v)", desc, item)
+			}
+
+			return nil
+		})
+	})
+}
+
+// Regex validates that a value (or every value of a repeatable flag or a variadic argument) matches the regular expression.
+// It panics if the regular expression can not be compiled.
+func Regex(pattern string) Validator {
+	expression := regexp.MustCompile(pattern)
+
+	return NewValidator("matches "+pattern, func(value interface{}) error {
+		return validateItems(value, func(item interface{}) error {
+			if !expression.MatchString(fmt.Sprint(item)) {
+				return fmt.Errorf("must match %s (got %v)", pattern, item)
+			}
+
+			return nil
+		})
+	})
+}
+
+// ExistingFile validates that a value (or every value of a repeatable flag or a variadic argument) is the path of an existing file.
+func ExistingFile() Validator {
+	return NewValidator("existing file", func(value interface{}) error {
+		return validateItems(value, func(item interface{}) error {
+			if info, err := os.Stat(fmt.Sprint(item)); err != nil || info.IsDir() {
+				return fmt.Errorf("must be an existing file (got %v)", item)
+			}
+
+			return nil
+		})
+	})
+}
+
+// ExistingDir validates that a value (or every value of a repeatable flag or a variadic argument) is the path of an existing directory.
+func ExistingDir() Validator {
+	return NewValidator("existing directory", func(value interface{}) error {
+		return validateItems(value, func(item interface{}) error {
+			if info, err := os.Stat(fmt.Sprint(item)); err != nil || !info.IsDir() {
+				return fmt.Errorf("must be an existing directory (got %v)", item)
+			}
+
+			return nil
+		})
+	})
+}
+
+// WritableDir validates that a value (or every value of a repeatable flag or a variadic argument) is the path of
+// an existing directory in which the process can create files.
+func WritableDir() Validator {
+	return NewValidator("writable directory", func(value interface{}) error {
+		return validateItems(value, func(item interface{}) error {
+			file, err := ioutil.TempFile(fmt.Sprint(item), ".commando-*")
+			if err != nil {
+				return fmt.Errorf("must be a writable directory (got %v)", item)
+			}
+
+			file.Close()
+			os.Remove(file.Name())
+
+			return nil
+		})
+	})
+}
+
+// NonEmpty validates that a string is not empty (or blank) and a repeatable flag or a variadic argument has at least one value.
+func NonEmpty() Validator {
+	return NewValidator("non-empty", func(value interface{}) error {
+		_value := reflect.ValueOf(value)
+
+		switch _value.Kind() {
+		case reflect.Slice, reflect.Map:
+			if _value.Len() == 0 {
+				return fmt.Errorf("must not be empty")
+			}
+		default:
+			if strings.TrimSpace(fmt.Sprint(value)) == "" {
+				return fmt.Errorf("must not be empty")
+			}
+		}
+
+		return nil
+	})
+}